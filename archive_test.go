@@ -0,0 +1,98 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "repos")
+
+	if err := os.MkdirAll(filepath.Join(dir, "acme"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "acme", "README"), []byte("readme\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeArchive(filepath.Join(root, "missing", "repos.tar.gz"), dir, true); err == nil {
+		t.Fatal("writeArchive into a missing dir succeeded")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "acme", "README")); err != nil {
+		t.Fatalf("failed archive removed files: %v", err)
+	}
+
+	name := filepath.Join(root, "repos.tar.gz")
+
+	if err := writeArchive(name, dir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("dir not removed: %v", err)
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []string{}
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, header.Name)
+	}
+
+	if want := []string{"repos/", "repos/acme/", "repos/acme/README"}; !slices.Equal(entries, want) {
+		t.Errorf("entries = %q, want %q", entries, want)
+	}
+}
+
+func TestInsideDir(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "/data/repos", want: true},
+		{name: "/data/repos/repos.tar.gz", want: true},
+		{name: "/data/repos.tar.gz", want: false},
+		{name: "/data/repos-backup/repos.tar.gz", want: false},
+		{name: "/data/..repos.tar.gz", want: false},
+	}
+
+	for _, tt := range tests {
+		got, err := insideDir(tt.name, "/data/repos")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("insideDir(%q) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
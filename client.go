@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/time/rate"
+)
+
+const (
+	tokenTypePAT   = "pat"
+	tokenTypeOAuth = "oauth"
+	tokenTypeJob   = "job"
+)
+
+// newClient builds the GitLab API client for the token type. A positive
+// rateLimit caps the requests per second. A nil transport keeps the
+// client default one. The client does not retry itself, API calls are
+// retried by the cloner up to its max retries.
+func newClient(host, token, tokenType string, rateLimit float64, transport *http.Transport) (*gitlab.Client, error) {
+	options := []gitlab.ClientOptionFunc{
+		gitlab.WithBaseURL(host + "/api/v4"),
+		gitlab.WithCustomRetryMax(0),
+	}
+
+	if rateLimit > 0 {
+		options = append(options, gitlab.WithCustomLimiter(rate.NewLimiter(rate.Limit(rateLimit), 1)))
+	}
+
+	if transport != nil {
+		options = append(options, gitlab.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}))
+	}
+
+	switch tokenType {
+	case tokenTypePAT:
+		return gitlab.NewClient(token, options...)
+	case tokenTypeOAuth:
+		return gitlab.NewOAuthClient(token, options...)
+	case tokenTypeJob:
+		return gitlab.NewJobClient(token, options...)
+	default:
+		return nil, fmt.Errorf("unknown token type %q", tokenType)
+	}
+}
+
+// newTransport returns the API transport for the proxy and TLS settings,
+// or nil when none is set. A proxy overrides the HTTPS_PROXY, HTTP_PROXY
+// and NO_PROXY environment variables the default transport honors.
+func newTransport(proxy string, caBundle []byte, insecureSkipTLS bool) (*http.Transport, error) {
+	if proxy == "" && len(caBundle) == 0 && !insecureSkipTLS {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: insecureSkipTLS,
+	}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("ca cert: no certificates found")
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return transport, nil
+}
+
+// tokenUsername returns the HTTP basic auth username GitLab expects for
+// git over HTTPS with the token type.
+func tokenUsername(tokenType string) string {
+	if tokenType == tokenTypeJob {
+		return "gitlab-ci-token"
+	}
+
+	return "oauth2"
+}
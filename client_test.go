@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		tokenType string
+		header    string
+		want      string
+	}{
+		{tokenType: tokenTypePAT, header: "PRIVATE-TOKEN", want: "secret"},
+		{tokenType: tokenTypeOAuth, header: "Authorization", want: "Bearer secret"},
+		{tokenType: tokenTypeJob, header: "JOB-TOKEN", want: "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tokenType, func(t *testing.T) {
+			var got http.Header
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":1}`))
+			}))
+			t.Cleanup(server.Close)
+
+			client, err := newClient(server.URL, "secret", tt.tokenType, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := client.Projects.GetProject(1, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if value := got.Get(tt.header); value != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, value, tt.want)
+			}
+		})
+	}
+
+	if _, err := newClient("http://127.0.0.1", "secret", "session", 0, nil); err == nil {
+		t.Error("unknown token type accepted")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := newClient(server.URL, "secret", tokenTypePAT, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+
+	for range 3 {
+		if _, _, err := client.Projects.GetProject(1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first request takes the burst, the other two wait 50ms each.
+	if elapsed := time.Since(started); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %s, want at least 100ms", elapsed)
+	}
+}
+
+func TestTokenUsername(t *testing.T) {
+	for tokenType, want := range map[string]string{
+		tokenTypePAT:   "oauth2",
+		tokenTypeOAuth: "oauth2",
+		tokenTypeJob:   "gitlab-ci-token",
+	} {
+		if got := tokenUsername(tokenType); got != want {
+			t.Errorf("tokenUsername(%s) = %q, want %q", tokenType, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	DestDir          *string          `yaml:"dest_dir"`
+	IgnoreProjectIDs *[]int           `yaml:"ignore_project_ids"`
+	IgnoreGroupIDs   *[]int           `yaml:"ignore_group_ids"`
+	GitlabHost       *string          `yaml:"gitlab_host"`
+	GitlabToken      *string          `yaml:"gitlab_token"`
+	GroupIDs         *[]int           `yaml:"group_ids"`
+	GroupPaths       *[]string        `yaml:"group_paths"`
+	ProjectIDs       *[]int           `yaml:"project_ids"`
+	Progress         *bool            `yaml:"progress"`
+	Instances        []InstanceConfig `yaml:"instances"`
+	DepthPerGroup    map[int]int      `yaml:"depth_per_group"`
+}
+
+// InstanceConfig describes one of several GitLab instances cloned in a
+// single run. An empty token or SSH user falls back to the top level one.
+type InstanceConfig struct {
+	GitlabHost  string   `yaml:"gitlab_host"`
+	GitlabToken string   `yaml:"gitlab_token"`
+	GroupIDs    []int    `yaml:"group_ids"`
+	GroupPaths  []string `yaml:"group_paths"`
+	ProjectIDs  []int    `yaml:"project_ids"`
+	SSHUser     string   `yaml:"ssh_user"`
+}
+
+func loadConfig(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	expandEnv(cfg.DestDir)
+	expandEnv(cfg.GitlabHost)
+	expandEnv(cfg.GitlabToken)
+
+	if cfg.GroupPaths != nil {
+		expandEnvs(*cfg.GroupPaths)
+	}
+
+	for i := range cfg.Instances {
+		expandEnv(&cfg.Instances[i].GitlabHost)
+		expandEnv(&cfg.Instances[i].GitlabToken)
+		expandEnv(&cfg.Instances[i].SSHUser)
+		expandEnvs(cfg.Instances[i].GroupPaths)
+	}
+
+	return cfg, nil
+}
+
+// expandEnv replaces ${VAR} and $VAR in a string config value.
+func expandEnv(value *string) {
+	if value != nil {
+		*value = os.ExpandEnv(*value)
+	}
+}
+
+// expandEnvs replaces ${VAR} and $VAR in every value of a string list.
+func expandEnvs(values []string) {
+	for i := range values {
+		expandEnv(&values[i])
+	}
+}
+
+// setFromConfig copies a config value into dst unless the flag was
+// explicitly set on the command line.
+func setFromConfig[T any](flag *pflag.FlagSet, name string, dst *T, value *T) {
+	if value == nil || flag.Changed(name) {
+		return
+	}
+
+	*dst = *value
+}
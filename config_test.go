@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestConfigFlagOverride(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.yaml")
+
+	data := []byte(`
+dest_dir: /srv/repos
+gitlab_host: https://gitlab.example.com
+group_ids: [1, 2]
+progress: true
+depth_per_group:
+  2: 1
+`)
+
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destDir, gitlabHost, groupIDs, progress := "repos", "https://gitlab.com", []int{}, false
+
+	flag := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flag.StringVar(&destDir, "dest-dir", destDir, "")
+	flag.StringVar(&gitlabHost, "gitlab-host", gitlabHost, "")
+	flag.IntSliceVar(&groupIDs, "group-ids", groupIDs, "")
+	flag.BoolVar(&progress, "progress", progress, "")
+
+	if err := flag.Parse([]string{"--dest-dir", "/tmp/cli", "--group-ids", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	setFromConfig(flag, "dest-dir", &destDir, cfg.DestDir)
+	setFromConfig(flag, "gitlab-host", &gitlabHost, cfg.GitlabHost)
+	setFromConfig(flag, "group-ids", &groupIDs, cfg.GroupIDs)
+	setFromConfig(flag, "progress", &progress, cfg.Progress)
+
+	if destDir != "/tmp/cli" {
+		t.Errorf("dest dir = %q, want the flag value", destDir)
+	}
+
+	if !slices.Equal(groupIDs, []int{3}) {
+		t.Errorf("group ids = %v, want the flag value", groupIDs)
+	}
+
+	if gitlabHost != "https://gitlab.example.com" || !progress {
+		t.Errorf("gitlab host = %q, progress = %t, want the config values", gitlabHost, progress)
+	}
+
+	if cfg.DepthPerGroup[2] != 1 {
+		t.Errorf("depth per group = %v", cfg.DepthPerGroup)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing config loaded")
+	}
+
+	name := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := os.WriteFile(name, []byte("group_ids: acme\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadConfig(name); err == nil {
+		t.Error("invalid config loaded")
+	}
+}
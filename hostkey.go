@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	hostKeyStrict    = "strict"
+	hostKeyAcceptNew = "accept-new"
+	hostKeyInsecure  = "insecure-ignore"
+)
+
+// newHostKeyCallback returns the SSH host key callback for mode. A nil
+// callback keeps the go-git default of the user's known_hosts files.
+func newHostKeyCallback(mode, knownHosts string) (gossh.HostKeyCallback, error) {
+	switch mode {
+	case hostKeyStrict:
+		if knownHosts == "" {
+			return nil, nil
+		}
+
+		return ssh.NewKnownHostsCallback(knownHosts)
+	case hostKeyAcceptNew:
+		if knownHosts == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+
+			knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+
+		return acceptNewCallback(knownHosts)
+	case hostKeyInsecure:
+		return gossh.InsecureIgnoreHostKey(), nil
+	default:
+		return nil, fmt.Errorf("unknown host key mode %q", mode)
+	}
+}
+
+// acceptNewCallback checks keys against the known_hosts file and records
+// the key of a host seen for the first time. Changed keys are rejected.
+func acceptNewCallback(name string) (gossh.HostKeyCallback, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Close()
+
+	callback, err := knownhosts.New(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+
+	return func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		err := callback(hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+
+		file, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+
+		line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+
+		if _, err := fmt.Fprintln(file, line); err != nil {
+			file.Close()
+
+			return err
+		}
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+
+		callback, err = knownhosts.New(name)
+
+		return err
+	}, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/xanzy/go-gitlab"
+)
+
+// logInspect is the inspect func of --in-memory runs, it logs the HEAD
+// and the number of files of every repo cloned in memory.
+func logInspect(_ context.Context, project *gitlab.Project, repo *git.Repository) error {
+	files, head, err := countFiles(repo)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("inspect repo",
+		slog.Int("project_id", project.ID),
+		slog.String("path", project.PathWithNamespace),
+		slog.String("head", head),
+		slog.Int("files", files),
+	)
+
+	return nil
+}
+
+// countFiles returns the number of files in the HEAD commit and the name
+// of HEAD.
+func countFiles(repo *git.Repository) (int, string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return 0, "", err
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return 0, "", err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return 0, "", err
+	}
+
+	files := 0
+
+	err = tree.Files().ForEach(func(*object.File) error {
+		files++
+
+		return nil
+	})
+
+	return files, head.Name().Short(), err
+}
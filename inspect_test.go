@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestCountFiles(t *testing.T) {
+	fs := memfs.New()
+
+	repo, err := git.Init(memory.NewStorage(), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"README", "docs/index.md"} {
+		if err := util.WriteFile(fs, name, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := work.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = work.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, head, err := countFiles(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files != 2 || head != "master" {
+		t.Errorf("countFiles = %d, %q, want 2, %q", files, head, "master")
+	}
+}
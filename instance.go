@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// hostDir returns the directory name an instance is cloned into.
+func hostDir(host string) (string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("gitlab host %q has no host name", host)
+	}
+
+	return u.Host, nil
+}
+
+func addStats(a, b cloner.Stats) cloner.Stats {
+	return cloner.Stats{
+		Cloned:     a.Cloned + b.Cloned,
+		Pulled:     a.Pulled + b.Pulled,
+		Skipped:    a.Skipped + b.Skipped,
+		Failed:     a.Failed + b.Failed,
+		Pruned:     a.Pruned + b.Pruned,
+		Corrupt:    a.Corrupt + b.Corrupt,
+		Empty:      a.Empty + b.Empty,
+		HookFailed: a.HookFailed + b.HookFailed,
+		Conflict:   a.Conflict + b.Conflict,
+		NoAccess:   a.NoAccess + b.NoAccess,
+		Duration:   a.Duration + b.Duration,
+		Commits:    a.Commits + b.Commits,
+	}
+}
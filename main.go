@@ -1,244 +1,738 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
-	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
-	"github.com/xanzy/go-gitlab"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
 )
 
-type RepoCloner struct {
-	destDir          string
-	client           *gitlab.Client
-	auth             transport.AuthMethod
-	ignoreProjectIDs []int
-	ignoreGroupIDs   []int
-	progress         io.Writer
-}
+// Startup and config errors exit with 1, a run where some repos failed
+// or were interrupted exits with exitPartial.
+const exitPartial = 2
 
-var listOptions = gitlab.ListOptions{
-	PerPage: 1000,
-	OrderBy: "name",
-	Sort:    "asc",
-}
+func main() {
+	started := time.Now()
 
-func (rc *RepoCloner) Group(groupID int) {
-	log := slog.With(slog.Int("group_id", groupID))
+	currentDir, err := os.Getwd()
+	if err != nil {
+		slog.Error("directory error", slog.String("error", err.Error()))
 
-	if slices.Contains(rc.ignoreGroupIDs, groupID) {
-		log.Warn("ignore group")
+		os.Exit(1)
+	}
 
-		return
+	opts := cloner.Options{
+		DestDir:          filepath.Join(currentDir, "repos"),
+		AuthMethod:       cloner.AuthMethodSSHAgent,
+		GroupIDs:         []int{},
+		GroupPaths:       []string{},
+		ProjectIDs:       []int{},
+		IgnoreProjectIDs: []int{},
+		IgnoreGroupIDs:   []int{},
+		Progress:         io.Discard,
+		Concurrency:      1,
+		MaxRetries:       3,
+		PerPage:          100,
+		PullStrategy:     cloner.PullStrategyMerge,
+		PathTemplate:     cloner.DefaultPathTemplate,
 	}
 
-	group, _, err := rc.client.Groups.GetGroup(
-		groupID,
-		&gitlab.GetGroupOptions{
-			ListOptions: listOptions,
-		},
-	)
-	if err != nil {
-		log.Error("get group error", slog.String("error", err.Error()))
+	gitlabHost := "https://gitlab.com"
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	mirrorToken := os.Getenv("MIRROR_TOKEN")
+	mirrorUsername := "oauth2"
 
-		return
+	if host := os.Getenv("GITLAB_HOST"); host != "" {
+		gitlabHost = host
 	}
 
-	log = log.With(slog.String("group", group.FullPath))
+	progress := false
+	configFile := ""
+	sshKey := ""
+	sshKeyPassphrase := ""
+	knownHosts := ""
+	sshUser := "git"
+	hostKeyMode := hostKeyStrict
+	logFormat := logFormatText
+	logLevel := "info"
+	logFile := ""
+	logAppend := false
+	fromStdin := false
+	dirMode := ""
+	owner := ""
+	maxGroupDepth := -1
+	jsonOutput := false
+	authOrder := []string{}
+	fallbackMethods := []string{}
+	reportSlowest := 0
+	ignoreFile := ""
+	validateConfig := false
+	progressBar := false
+	apiRateLimit := 0.0
+	tokenFile := ""
+	manifest := ""
+	tokenType := tokenTypePAT
+	skipAuthCheck := false
+	updatedAfter := ""
+	instances := []InstanceConfig{}
+	prefixHost := false
+	metricsAddr := ""
+	outputDirPerGroup := false
+	caCert := ""
+	archive := ""
+	archiveRemove := false
 
-	log.Info("get group repos")
+	flag := pflag.NewFlagSet(path.Base(os.Args[0]), pflag.ContinueOnError)
 
-	projects, _, err := rc.client.Groups.ListGroupProjects(
-		group.ID,
-		&gitlab.ListGroupProjectsOptions{
-			ListOptions: listOptions,
-		},
-	)
-	if err != nil {
-		log.Error("list projects error", slog.String("error", err.Error()))
+	flag.StringVar(&opts.DestDir, "dest-dir", opts.DestDir, "")
+	flag.IntSliceVar(&opts.IgnoreProjectIDs, "ignore-project-ids", opts.IgnoreProjectIDs, "")
+	flag.IntSliceVar(&opts.IgnoreGroupIDs, "ignore-group-ids", opts.IgnoreGroupIDs, "")
+	flag.StringVar(&gitlabHost, "gitlab-host", gitlabHost, "")
+	flag.StringVar(&gitlabToken, "gitlab-token", gitlabToken, "")
+	flag.IntSliceVar(&opts.GroupIDs, "group-ids", opts.GroupIDs, "")
+	flag.StringSliceVar(&opts.GroupPaths, "group-paths", opts.GroupPaths, "")
+	flag.IntSliceVar(&opts.ProjectIDs, "project-ids", opts.ProjectIDs, "")
+	flag.BoolVar(&progress, "progress", progress, "")
+	flag.StringVar(&opts.AuthMethod, "auth-method", opts.AuthMethod, "")
+	flag.IntVar(&opts.Concurrency, "concurrency", opts.Concurrency, "")
+	flag.StringVar(&configFile, "config", configFile, "")
+	flag.IntVar(&opts.MaxRetries, "max-retries", opts.MaxRetries, "")
+	flag.StringVar(&sshKey, "ssh-key", sshKey, "")
+	flag.StringVar(&sshKeyPassphrase, "ssh-key-passphrase", sshKeyPassphrase, "")
+	flag.StringVar(&knownHosts, "known-hosts", knownHosts, "")
+	flag.StringVar(&sshUser, "ssh-user", sshUser, "")
+	flag.StringVar(&hostKeyMode, "host-key-mode", hostKeyMode, "")
+	flag.BoolVar(&opts.All, "all", opts.All, "")
+	flag.BoolVar(&opts.Owned, "owned", opts.Owned, "")
+	flag.DurationVar(&opts.CloneTimeout, "clone-timeout", opts.CloneTimeout, "")
+	flag.BoolVar(&opts.IncludeArchived, "include-archived", opts.IncludeArchived, "")
+	flag.StringVar(&opts.IncludeRegex, "include-regex", opts.IncludeRegex, "")
+	flag.StringVar(&opts.ExcludeRegex, "exclude-regex", opts.ExcludeRegex, "")
+	flag.StringVar(&opts.Branch, "branch", opts.Branch, "")
+	flag.BoolVar(&opts.Bare, "bare", opts.Bare, "")
+	flag.BoolVar(&opts.LFS, "lfs", opts.LFS, "")
+	flag.StringVar(&logFormat, "log-format", logFormat, "")
+	flag.StringVar(&logLevel, "log-level", logLevel, "")
+	flag.StringVar(&logFile, "log-file", logFile, "")
+	flag.BoolVar(&logAppend, "log-append", logAppend, "")
+	flag.BoolVar(&progressBar, "progress-bar", progressBar, "")
+	flag.BoolVar(&opts.Prune, "prune", opts.Prune, "")
+	flag.IntVar(&opts.PerPage, "per-page", opts.PerPage, "")
+	flag.StringVar(&opts.PullStrategy, "pull-strategy", opts.PullStrategy, "")
+	flag.StringSliceVar(&opts.Visibility, "visibility", opts.Visibility, "")
+	flag.IntVar(&opts.Depth, "depth", opts.Depth, "")
+	flag.BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "")
+	flag.BoolVar(&opts.SkipPull, "skip-pull", opts.SkipPull, "")
+	flag.StringVar(&opts.MirrorTo, "mirror-to", opts.MirrorTo, "")
+	flag.StringVar(&mirrorUsername, "mirror-username", mirrorUsername, "")
+	flag.StringVar(&mirrorToken, "mirror-token", mirrorToken, "")
+	flag.Float64Var(&apiRateLimit, "api-rate-limit", apiRateLimit, "")
+	flag.BoolVar(&opts.IncludeShared, "include-shared", opts.IncludeShared, "")
+	flag.StringVar(&tokenFile, "token-file", tokenFile, "")
+	flag.StringVar(&opts.Ref, "ref", opts.Ref, "")
+	flag.StringVar(&manifest, "manifest", manifest, "")
+	flag.StringArrayVar(&opts.Topics, "topic", opts.Topics, "")
+	flag.BoolVar(&opts.RecurseSubmodules, "recurse-submodules", opts.RecurseSubmodules, "")
+	flag.StringVar(&opts.StateFile, "state-file", opts.StateFile, "")
+	flag.BoolVar(&opts.ForceRefresh, "force-refresh", opts.ForceRefresh, "")
+	flag.StringVar(&tokenType, "token-type", tokenType, "")
+	flag.StringArrayVar(&opts.ExcludePrefixes, "exclude-path-prefix", opts.ExcludePrefixes, "")
+	flag.BoolVar(&opts.Starred, "starred", opts.Starred, "")
+	flag.IntVar(&opts.MaxRepoSizeMB, "max-repo-size-mb", opts.MaxRepoSizeMB, "")
+	flag.StringVar(&opts.PathTemplate, "path-template", opts.PathTemplate, "")
+	flag.BoolVar(&opts.Verify, "verify", opts.Verify, "")
+	flag.BoolVar(&skipAuthCheck, "skip-auth-check", skipAuthCheck, "")
+	flag.StringVar(&opts.Proxy, "proxy", opts.Proxy, "")
+	flag.StringVar(&updatedAfter, "updated-after", updatedAfter, "")
+	flag.BoolVar(&prefixHost, "prefix-host", prefixHost, "")
+	flag.BoolVar(&opts.GC, "gc", opts.GC, "")
+	flag.StringVar(&opts.PostCloneHook, "post-clone-hook", opts.PostCloneHook, "")
+	flag.StringVar(&metricsAddr, "metrics-addr", metricsAddr, "")
+	flag.BoolVar(&opts.OnlyEmptyDir, "only-empty-dir", opts.OnlyEmptyDir, "")
+	flag.BoolVar(&opts.FetchOnly, "fetch-only", opts.FetchOnly, "")
+	flag.IntVar(&opts.MaxClonesPerHost, "max-concurrent-clones-per-host", opts.MaxClonesPerHost, "")
+	flag.StringSliceVar(&opts.IgnoreGroupPaths, "ignore-group-paths", opts.IgnoreGroupPaths, "")
+	flag.IntVar(&opts.Limit, "limit", opts.Limit, "")
+	flag.StringArrayVar(&opts.RefSpecs, "refspec", opts.RefSpecs, "")
+	flag.BoolVar(&outputDirPerGroup, "output-dir-per-group", outputDirPerGroup, "")
+	flag.StringVar(&caCert, "ca-cert", caCert, "")
+	flag.BoolVar(&opts.InsecureSkipTLS, "insecure-skip-verify", opts.InsecureSkipTLS, "")
+	flag.BoolVar(&opts.CloneOnlyNew, "clone-only-new", opts.CloneOnlyNew, "")
+	flag.StringVar(&archive, "archive", archive, "")
+	flag.BoolVar(&archiveRemove, "archive-remove", archiveRemove, "")
+	flag.BoolVar(&opts.FailFast, "fail-fast", opts.FailFast, "")
+	flag.BoolVar(&opts.InMemory, "in-memory", opts.InMemory, "")
+	flag.BoolVar(&opts.TagsOnly, "clone-tags-only", opts.TagsOnly, "")
+	flag.BoolVar(&fromStdin, "from-stdin", fromStdin, "")
+	flag.BoolVar(&opts.CountOnly, "count-only", opts.CountOnly, "")
+	flag.BoolVar(&opts.StashBeforePull, "stash-before-pull", opts.StashBeforePull, "")
+	flag.BoolVar(&opts.IncludeWikis, "include-wikis", opts.IncludeWikis, "")
+	flag.StringVar(&dirMode, "dir-mode", dirMode, "")
+	flag.StringVar(&owner, "owner", owner, "")
+	flag.BoolVar(&opts.NoForcePull, "no-force-pull", opts.NoForcePull, "")
+	flag.IntVar(&maxGroupDepth, "max-group-depth", maxGroupDepth, "")
+	flag.BoolVar(&jsonOutput, "json-output", jsonOutput, "")
+	flag.StringVar(&opts.BranchPattern, "branch-pattern", opts.BranchPattern, "")
+	flag.BoolVar(&opts.RefreshRemotes, "refresh-remotes", opts.RefreshRemotes, "")
+	flag.StringSliceVar(&authOrder, "auth-order", authOrder, "")
+	flag.StringSliceVar(&opts.SparsePaths, "sparse-paths", opts.SparsePaths, "")
+	flag.IntVar(&reportSlowest, "report-slowest", reportSlowest, "")
+	flag.StringVar(&ignoreFile, "ignore-file", ignoreFile, "")
+	flag.BoolVar(&opts.DedupByID, "dedup-by-id", opts.DedupByID, "")
+	flag.BoolVar(&validateConfig, "validate-config", validateConfig, "")
 
-		return
+	if err := flag.Parse(os.Args[1:]); err != nil {
+		if !errors.Is(pflag.ErrHelp, err) {
+			slog.Error("flag error", slog.String("error", err.Error()))
+		}
+
+		os.Exit(1)
 	}
 
-	for _, project := range projects {
-		rc.gitClone(project, group.FullPath)
+	var logWriter io.Writer = os.Stderr
+
+	if logFile != "" {
+		file, err := openLogFile(logFile, logAppend)
+		if err != nil {
+			slog.Error("log file error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		defer file.Close()
+
+		logWriter = io.MultiWriter(os.Stderr, file)
 	}
 
-	groups, _, err := rc.client.Groups.ListSubGroups(
-		group.ID,
-		&gitlab.ListSubGroupsOptions{
-			ListOptions: listOptions,
-		},
-	)
+	handler, err := newLogHandler(logWriter, logFormat, logLevel)
 	if err != nil {
-		log.Error("list subgroups error", slog.String("error", err.Error()))
+		slog.Error("log error", slog.String("error", err.Error()))
 
-		return
+		os.Exit(1)
 	}
 
-	for _, group := range groups {
-		rc.Group(group.ID)
+	slog.SetDefault(slog.New(handler))
+
+	if configFile != "" {
+		cfg, err := loadConfig(configFile)
+		if err != nil {
+			slog.Error("config error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		setFromConfig(flag, "dest-dir", &opts.DestDir, cfg.DestDir)
+		setFromConfig(flag, "ignore-project-ids", &opts.IgnoreProjectIDs, cfg.IgnoreProjectIDs)
+		setFromConfig(flag, "ignore-group-ids", &opts.IgnoreGroupIDs, cfg.IgnoreGroupIDs)
+		setFromConfig(flag, "gitlab-host", &gitlabHost, cfg.GitlabHost)
+		setFromConfig(flag, "gitlab-token", &gitlabToken, cfg.GitlabToken)
+		setFromConfig(flag, "group-ids", &opts.GroupIDs, cfg.GroupIDs)
+		setFromConfig(flag, "group-paths", &opts.GroupPaths, cfg.GroupPaths)
+		setFromConfig(flag, "project-ids", &opts.ProjectIDs, cfg.ProjectIDs)
+		setFromConfig(flag, "progress", &progress, cfg.Progress)
+
+		instances = cfg.Instances
+		opts.GroupDepths = cfg.DepthPerGroup
 	}
-}
 
-func (rc *RepoCloner) Project(projectID int) {
-	log := slog.With(slog.Int("project_id", projectID))
+	if !filepath.IsAbs(opts.DestDir) {
+		opts.DestDir = filepath.Join(currentDir, opts.DestDir)
+	}
+
+	if outputDirPerGroup {
+		opts.GroupRootDir = currentDir
+	}
 
-	if slices.Contains(rc.ignoreProjectIDs, projectID) {
-		log.Warn("ignore project")
+	if updatedAfter != "" {
+		opts.UpdatedAfter, err = time.Parse(time.RFC3339, updatedAfter)
+		if err != nil {
+			slog.Error("updated after error", slog.String("error", err.Error()))
 
-		return
+			os.Exit(1)
+		}
 	}
 
-	project, _, err := rc.client.Projects.GetProject(
-		projectID,
-		&gitlab.GetProjectOptions{},
-	)
+	if caCert != "" {
+		opts.CABundle, err = os.ReadFile(caCert)
+		if err != nil {
+			slog.Error("ca cert error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+	}
+
+	apiTransport, err := newTransport(opts.Proxy, opts.CABundle, opts.InsecureSkipTLS)
 	if err != nil {
-		log.Error("get project error", slog.String("error", err.Error()))
+		slog.Error("transport error", slog.String("error", err.Error()))
 
-		return
+		os.Exit(1)
 	}
 
-	rc.gitClone(project, "")
-}
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			slog.Error("token file error", slog.String("error", err.Error()))
 
-func (rc *RepoCloner) gitClone(project *gitlab.Project, dest string) {
-	subPath := path.Join(dest, project.Path)
+			os.Exit(1)
+		}
 
-	log := slog.With(slog.Int("project_id", project.ID), slog.String("path", subPath))
+		fileToken := strings.TrimSpace(string(data))
 
-	log.Info("get repo")
+		if flag.Changed("gitlab-token") && gitlabToken != "" && gitlabToken != fileToken {
+			slog.Error("token error", slog.String("error", "--gitlab-token and --token-file conflict"))
 
-	subPath = path.Join(rc.destDir, subPath)
+			os.Exit(1)
+		}
 
-	_, err := git.PlainClone(
-		subPath,
-		false,
-		&git.CloneOptions{
-			URL:      project.SSHURLToRepo,
-			Auth:     rc.auth,
-			Progress: rc.progress,
-		},
-	)
-	if err != nil && !errors.Is(err, git.ErrRepositoryAlreadyExists) {
-		log.Error("clone repo error", slog.String("error", err.Error()))
+		gitlabToken = fileToken
+	}
 
-		return
+	// Without instances in the config the single host is cloned straight
+	// into the destination directory unless --prefix-host is set,
+	// otherwise every instance gets its own subdirectory named by host.
+	multiInstance := len(instances) > 0
+
+	if ignoreFile != "" {
+		file, err := os.Open(ignoreFile)
+		if err != nil {
+			slog.Error("ignore file error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		groupIDs, groupPaths, projectIDs, err := readTargets(file)
+		file.Close()
+
+		if err != nil {
+			slog.Error("ignore file error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		opts.IgnoreGroupIDs = append(opts.IgnoreGroupIDs, groupIDs...)
+		opts.IgnoreGroupPaths = append(opts.IgnoreGroupPaths, groupPaths...)
+		opts.IgnoreProjectIDs = append(opts.IgnoreProjectIDs, projectIDs...)
 	}
 
-	repo, err := git.PlainOpen(subPath)
-	if err != nil {
-		log.Error("open repo error", slog.String("error", err.Error()))
+	if fromStdin {
+		if multiInstance {
+			slog.Error("stdin error", slog.String("error", "--from-stdin can not be used with instances"))
 
-		return
+			os.Exit(1)
+		}
+
+		groupIDs, groupPaths, projectIDs, err := readTargets(os.Stdin)
+		if err != nil {
+			slog.Error("stdin error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		opts.GroupIDs = append(opts.GroupIDs, groupIDs...)
+		opts.GroupPaths = append(opts.GroupPaths, groupPaths...)
+		opts.ProjectIDs = append(opts.ProjectIDs, projectIDs...)
 	}
 
-	work, err := repo.Worktree()
-	if err != nil {
-		log.Error("worktree repo error", slog.String("error", err.Error()))
+	if !multiInstance {
+		instances = []InstanceConfig{{
+			GitlabHost:  gitlabHost,
+			GitlabToken: gitlabToken,
+			GroupIDs:    opts.GroupIDs,
+			GroupPaths:  opts.GroupPaths,
+			ProjectIDs:  opts.ProjectIDs,
+		}}
+	}
 
-		return
+	// Tokens are masked by value in the logs, whatever their format.
+	addSecret(gitlabToken)
+	addSecret(mirrorToken)
+	addSecret(sshKeyPassphrase)
+
+	for i := range instances {
+		if instances[i].GitlabToken == "" {
+			instances[i].GitlabToken = gitlabToken
+		}
+
+		addSecret(instances[i].GitlabToken)
+
+		if instances[i].SSHUser == "" {
+			instances[i].SSHUser = sshUser
+		}
+
+		if instances[i].GitlabToken == "" {
+			slog.Error("token error",
+				slog.String("gitlab_host", instances[i].GitlabHost),
+				slog.String("error", "gitlab token is empty, set --gitlab-token, --token-file or GITLAB_TOKEN"),
+			)
+
+			os.Exit(1)
+		}
 	}
 
-	err = work.Pull(
-		&git.PullOptions{
-			RemoteName: "origin",
-			Force:      true,
-			Progress:   rc.progress,
-		},
-	)
-	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		log.Error("pull repo error", slog.String("error", err.Error()))
+	// Stdout is kept for the JSON report alone.
+	var progressWriter io.Writer = os.Stdout
+	if jsonOutput {
+		progressWriter = os.Stderr
+	}
 
-		return
+	if progress {
+		opts.Progress = progressWriter
 	}
-}
 
-func main() {
-	currentDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		slog.Error("directory error", slog.String("error", err.Error()))
+	if progressBar {
+		opts.ProgressBar = progressWriter
+	}
 
-		os.Exit(1)
+	// With --auth-order the first method is the primary one and the rest
+	// are tried in order when it fails to authenticate a clone.
+	if len(authOrder) > 0 {
+		if flag.Changed("auth-method") {
+			slog.Error("auth method error", slog.String("error", "--auth-method and --auth-order conflict"))
+
+			os.Exit(1)
+		}
+
+		opts.AuthMethod = authOrder[0]
+		fallbackMethods = authOrder[1:]
 	}
 
-	rc := &RepoCloner{
-		destDir:          path.Join(currentDir, "repos"),
-		ignoreProjectIDs: []int{},
-		ignoreGroupIDs:   []int{},
-		progress:         io.Discard,
+	if sshKey != "" && len(authOrder) == 0 {
+		if flag.Changed("auth-method") && opts.AuthMethod != cloner.AuthMethodSSHKey {
+			slog.Error("auth method error",
+				slog.String("auth_method", opts.AuthMethod),
+				slog.String("error", "--ssh-key requires ssh-key auth method"),
+			)
+
+			os.Exit(1)
+		}
+
+		opts.AuthMethod = cloner.AuthMethodSSHKey
 	}
 
-	gitlabHost := "https://gitlab.com"
-	gitlabToken := ""
-	groupIDs := []int{}
-	projectIDs := []int{}
-	progress := false
+	if dirMode != "" {
+		mode, err := strconv.ParseUint(dirMode, 8, 32)
+		if err != nil || mode > 0o7777 {
+			slog.Error("dir mode error", slog.String("error", fmt.Sprintf("invalid octal mode %q", dirMode)))
 
-	flag := pflag.NewFlagSet(path.Base(os.Args[0]), pflag.ContinueOnError)
+			os.Exit(1)
+		}
 
-	flag.StringVar(&rc.destDir, "dest-dir", "./repos", "")
-	flag.IntSliceVar(&rc.ignoreProjectIDs, "ignore-project-ids", rc.ignoreProjectIDs, "")
-	flag.IntSliceVar(&rc.ignoreGroupIDs, "ignore-group-ids", rc.ignoreGroupIDs, "")
-	flag.StringVar(&gitlabHost, "gitlab-host", gitlabHost, "")
-	flag.StringVar(&gitlabToken, "gitlab-token", gitlabToken, "")
-	flag.IntSliceVar(&groupIDs, "group-ids", groupIDs, "")
-	flag.IntSliceVar(&projectIDs, "project-ids", projectIDs, "")
-	flag.BoolVar(&progress, "progress", progress, "")
+		opts.DirMode = fileMode(mode)
+	}
 
-	if err := flag.Parse(os.Args[1:]); err != nil {
-		if !errors.Is(pflag.ErrHelp, err) {
-			slog.Error("flag error", slog.String("error", err.Error()))
+	if owner != "" {
+		opts.Owner, err = parseOwner(owner)
+		if err != nil {
+			slog.Error("owner error", slog.String("error", err.Error()))
+
+			os.Exit(1)
 		}
+	}
 
-		os.Exit(1)
+	// The mirror host is not the GitLab host, its credentials are never
+	// taken from the GitLab auth.
+	if mirrorToken != "" {
+		opts.MirrorAuth = &githttp.BasicAuth{
+			Username: mirrorUsername,
+			Password: mirrorToken,
+		}
 	}
 
-	if progress {
-		rc.progress = os.Stdout
+	if flag.Changed("max-group-depth") {
+		opts.MaxGroupDepth = &maxGroupDepth
 	}
 
-	client, err := gitlab.NewClient(
-		gitlabToken,
-		gitlab.WithBaseURL(gitlabHost+"/api/v4"),
-	)
-	if err != nil {
-		slog.Error("client error", slog.String("error", err.Error()))
+	if opts.InMemory {
+		opts.Inspect = logInspect
+	}
+
+	if archive != "" && (opts.InMemory || outputDirPerGroup) {
+		slog.Error("archive error", slog.String("error", "--archive can not be used with --in-memory or --output-dir-per-group"))
 
 		os.Exit(1)
 	}
 
-	if _, _, err := client.Users.CurrentUser(); err != nil {
-		slog.Error("current user error", slog.String("error", err.Error()))
+	// Removing the dest dir after archiving would delete an archive
+	// written into it.
+	if archive != "" && archiveRemove {
+		inside, err := insideDir(archive, opts.DestDir)
+		if err != nil {
+			slog.Error("archive error", slog.String("error", err.Error()))
 
-		os.Exit(1)
+			os.Exit(1)
+		}
+
+		if inside {
+			slog.Error("archive error", slog.String("error", "--archive can not be inside --dest-dir with --archive-remove"))
+
+			os.Exit(1)
+		}
 	}
 
-	rc.client = client
+	// Job tokens can not read the current user, the auth check would
+	// always fail and so would validating the token.
+	if tokenType == tokenTypeJob {
+		if validateConfig {
+			slog.Error("token error", slog.String("error", "--validate-config can not be used with --token-type job"))
 
-	auth, err := ssh.NewSSHAgentAuth("git")
+			os.Exit(1)
+		}
+
+		skipAuthCheck = true
+	}
+
+	hostKeyCallback, err := newHostKeyCallback(hostKeyMode, knownHosts)
 	if err != nil {
-		slog.Error("auth error", slog.String("error", err.Error()))
+		slog.Error("host key error", slog.String("error", err.Error()))
 
 		os.Exit(1)
 	}
 
-	rc.auth = auth
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	for _, gid := range groupIDs {
-		rc.Group(gid)
+	if metricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		opts.Metrics = cloner.NewMetrics(registry)
+
+		server, err := serveMetrics(ctx, metricsAddr, registry)
+		if err != nil {
+			slog.Error("metrics error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		defer server.Close()
 	}
 
-	for _, pid := range projectIDs {
-		rc.Project(pid)
+	cloners := []*cloner.Cloner{}
+	invalid := 0
+
+	for _, instance := range instances {
+		log := slog.With(slog.String("gitlab_host", instance.GitlabHost))
+
+		instanceOpts := opts
+		instanceOpts.GroupIDs = instance.GroupIDs
+		instanceOpts.GroupPaths = instance.GroupPaths
+		instanceOpts.ProjectIDs = instance.ProjectIDs
+
+		if multiInstance || prefixHost {
+			dir, err := hostDir(instance.GitlabHost)
+			if err != nil {
+				log.Error("instance error", slog.String("error", err.Error()))
+
+				os.Exit(1)
+			}
+
+			instanceOpts.DestDir = filepath.Join(opts.DestDir, dir)
+
+			if multiInstance && opts.StateFile != "" {
+				instanceOpts.StateFile = opts.StateFile + "." + dir
+			}
+		}
+
+		instanceOpts.Client, err = newClient(instance.GitlabHost, instance.GitlabToken, tokenType, apiRateLimit, apiTransport)
+		if err != nil {
+			log.Error("client error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		instanceOpts.Auth, err = newAuth(opts.AuthMethod, tokenUsername(tokenType), instance.GitlabToken, instance.SSHUser, sshKey, sshKeyPassphrase, hostKeyCallback)
+		if err != nil {
+			log.Error("auth error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		for _, method := range fallbackMethods {
+			auth, err := newAuth(method, tokenUsername(tokenType), instance.GitlabToken, instance.SSHUser, sshKey, sshKeyPassphrase, hostKeyCallback)
+			if err != nil {
+				log.Error("auth error", slog.String("auth_method", method), slog.String("error", err.Error()))
+
+				os.Exit(1)
+			}
+
+			instanceOpts.FallbackAuths = append(instanceOpts.FallbackAuths, cloner.AuthOption{
+				Method: method,
+				Auth:   auth,
+			})
+		}
+
+		rc, err := cloner.New(instanceOpts)
+		if err != nil {
+			log.Error("cloner error", slog.String("error", err.Error()))
+
+			os.Exit(1)
+		}
+
+		if validateConfig {
+			invalid += logValidate(log, rc.Validate(ctx))
+
+			continue
+		}
+
+		if !skipAuthCheck {
+			if err := rc.CheckAuth(ctx); err != nil {
+				log.Error("current user error", slog.String("error", err.Error()))
+
+				os.Exit(1)
+			}
+		}
+
+		log.Info("auth", slog.String("auth_method", opts.AuthMethod))
+
+		cloners = append(cloners, rc)
+	}
+
+	if validateConfig {
+		if invalid > 0 {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	errs := []error{}
+	results := []cloner.Result{}
+	stats := cloner.Stats{}
+
+	for _, rc := range cloners {
+		errs = append(errs, rc.Run(ctx))
+		results = append(results, rc.Results()...)
+		stats = addStats(stats, rc.Stats())
+	}
+
+	err = errors.Join(errs...)
+	if ctx.Err() != nil {
+		slog.Warn("interrupted, remaining repos skipped")
+	}
+
+	if manifest != "" {
+		if err := writeManifest(manifest, results); err != nil {
+			slog.Error("manifest error", slog.String("error", err.Error()))
+		}
+	}
+
+	// Dry runs and counts clone nothing, there is nothing to archive.
+	if archive != "" && !opts.DryRun && !opts.CountOnly && ctx.Err() == nil {
+		if err := writeArchive(archive, opts.DestDir, archiveRemove); err != nil {
+			slog.Error("archive error", slog.String("error", err.Error()))
+		} else {
+			slog.Info("archive", slog.String("path", archive))
+		}
+	}
+
+	slog.Info("summary",
+		slog.Int("cloned", stats.Cloned),
+		slog.Int("pulled", stats.Pulled),
+		slog.Int("skipped", stats.Skipped),
+		slog.Int("failed", stats.Failed),
+		slog.Int("pruned", stats.Pruned),
+		slog.Int("corrupt", stats.Corrupt),
+		slog.Int("empty", stats.Empty),
+		slog.Int("hook_failed", stats.HookFailed),
+		slog.Int("conflict", stats.Conflict),
+		slog.Int("no_access", stats.NoAccess),
+		slog.Int("commits", stats.Commits),
+		slog.Duration("duration", stats.Duration),
+	)
+
+	if reportSlowest > 0 {
+		logSlowest(results, reportSlowest)
+	}
+
+	if opts.DryRun {
+		logPlan(results)
+	}
+
+	if jsonOutput {
+		report := runReport{
+			StartedAt: started,
+			Duration:  time.Since(started).Seconds(),
+			Config:    newReportConfig(opts, instances),
+			Stats:     stats,
+			Results:   results,
+		}
+
+		if err != nil {
+			report.Error = redact(err.Error())
+		}
+
+		if err := writeReport(os.Stdout, report); err != nil {
+			slog.Error("json output error", slog.String("error", err.Error()))
+		}
+	}
+
+	for _, result := range results {
+		if result.Status == cloner.StatusCorrupt {
+			slog.Error("corrupt repo", slog.String("path", result.PathWithNamespace))
+		}
+	}
+
+	if code := exitCode(err, stats); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// exitCode returns exitPartial when the run failed or left repos failed,
+// corrupt or in conflict, and 0 otherwise.
+func exitCode(err error, stats cloner.Stats) int {
+	if err != nil || stats.Failed > 0 || stats.Corrupt > 0 || stats.Conflict > 0 {
+		return exitPartial
+	}
+
+	return 0
+}
+
+func newAuth(method, username, token, sshUser, sshKey, sshKeyPassphrase string, hostKeyCallback gossh.HostKeyCallback) (transport.AuthMethod, error) {
+	switch method {
+	case cloner.AuthMethodSSHAgent:
+		auth, err := ssh.NewSSHAgentAuth(sshUser)
+		if err != nil {
+			return nil, err
+		}
+
+		if hostKeyCallback != nil {
+			auth.HostKeyCallback = hostKeyCallback
+		}
+
+		return auth, nil
+	case cloner.AuthMethodSSHKey:
+		auth, err := ssh.NewPublicKeysFromFile(sshUser, sshKey, sshKeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		if hostKeyCallback != nil {
+			auth.HostKeyCallback = hostKeyCallback
+		}
+
+		return auth, nil
+	case cloner.AuthMethodHTTPToken:
+		return &githttp.BasicAuth{
+			Username: username,
+			Password: token,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth method %q", method)
 	}
 }
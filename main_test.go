@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		stats cloner.Stats
+		want  int
+	}{
+		{name: "ok", stats: cloner.Stats{Cloned: 2, Pulled: 1, Skipped: 1, Empty: 1, NoAccess: 1}, want: 0},
+		{name: "failed", stats: cloner.Stats{Cloned: 2, Failed: 1}, want: exitPartial},
+		{name: "corrupt", stats: cloner.Stats{Corrupt: 1}, want: exitPartial},
+		{name: "conflict", stats: cloner.Stats{Conflict: 1}, want: exitPartial},
+		{name: "interrupted", err: context.Canceled, stats: cloner.Stats{Cloned: 1}, want: exitPartial},
+	}
+
+	for _, tt := range tests {
+		if got := exitCode(tt.err, tt.stats); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
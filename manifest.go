@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+func writeManifest(name string, results []cloner.Result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, append(data, '\n'), 0o644)
+}
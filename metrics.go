@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics exposes the registry on addr until ctx is done.
+func serveMetrics(ctx context.Context, addr string, registry *prometheus.Registry) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Handler: mux,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server error", slog.String("error", err.Error()))
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		server.Close()
+	}()
+
+	slog.Info("metrics", slog.String("addr", listener.Addr().String()))
+
+	return server, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// fileMode converts an octal mode like 2775 to a file mode, the special
+// bits are not stored as is in os.FileMode.
+func fileMode(mode uint64) os.FileMode {
+	fileMode := os.FileMode(mode & 0o777)
+
+	if mode&0o4000 != 0 {
+		fileMode |= os.ModeSetuid
+	}
+
+	if mode&0o2000 != 0 {
+		fileMode |= os.ModeSetgid
+	}
+
+	if mode&0o1000 != 0 {
+		fileMode |= os.ModeSticky
+	}
+
+	return fileMode
+}
+
+// parseOwner resolves user[:group], by name or numeric id. Without a
+// group the group is left unchanged.
+func parseOwner(owner string) (*cloner.Owner, error) {
+	userName, groupName, hasGroup := strings.Cut(owner, ":")
+
+	uid, err := strconv.Atoi(userName)
+	if err != nil {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return nil, err
+		}
+
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return nil, err
+		}
+	}
+
+	gid := -1
+
+	if hasGroup {
+		gid, err = strconv.Atoi(groupName)
+		if err != nil {
+			g, err := user.LookupGroup(groupName)
+			if err != nil {
+				return nil, err
+			}
+
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &cloner.Owner{UID: uid, GID: gid}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		mode uint64
+		want os.FileMode
+	}{
+		{mode: 0o755, want: 0o755},
+		{mode: 0o2775, want: 0o775 | os.ModeSetgid},
+		{mode: 0o4700, want: 0o700 | os.ModeSetuid},
+		{mode: 0o1777, want: 0o777 | os.ModeSticky},
+	}
+
+	for _, tt := range tests {
+		if got := fileMode(tt.mode); got != tt.want {
+			t.Errorf("fileMode(%o) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	tests := []struct {
+		owner   string
+		want    cloner.Owner
+		wantErr bool
+	}{
+		{owner: "1000", want: cloner.Owner{UID: 1000, GID: -1}},
+		{owner: "1000:1001", want: cloner.Owner{UID: 1000, GID: 1001}},
+		{owner: "root:0", want: cloner.Owner{UID: 0, GID: 0}},
+		{owner: "no-such-user-xyz", wantErr: true},
+		{owner: "1000:no-such-group-xyz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOwner(tt.owner)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOwner(%q) err = %v, want error %t", tt.owner, err, tt.wantErr)
+
+			continue
+		}
+
+		if err == nil && *got != tt.want {
+			t.Errorf("parseOwner(%q) = %+v, want %+v", tt.owner, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package cloner
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+)
+
+// accessLevel returns the highest role of the token on the project, or
+// NoPermissions when the listing did not include permissions.
+func accessLevel(project *gitlab.Project) gitlab.AccessLevelValue {
+	level := gitlab.NoPermissions
+
+	if project.Permissions == nil {
+		return level
+	}
+
+	if access := project.Permissions.ProjectAccess; access != nil {
+		level = max(level, access.AccessLevel)
+	}
+
+	if access := project.Permissions.GroupAccess; access != nil {
+		level = max(level, access.AccessLevel)
+	}
+
+	return level
+}
+
+// noCodeAccess reports whether the role of the token is known and too
+// low to read the code, guests only see the repos of public and internal
+// projects.
+func noCodeAccess(project *gitlab.Project) bool {
+	level := accessLevel(project)
+
+	return project.Visibility == gitlab.PrivateVisibility &&
+		level != gitlab.NoPermissions &&
+		level < gitlab.ReporterPermissions
+}
+
+// isNoAccess reports whether git was refused access to the repo.
+func isNoAccess(err error) bool {
+	return errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(err.Error(), "not allowed to download code")
+}
@@ -0,0 +1,104 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+)
+
+// AuthOption is an auth method tried when the ones before it fail to
+// authenticate a clone.
+type AuthOption struct {
+	Method string
+	Auth   transport.AuthMethod
+}
+
+// auths returns the configured auth method followed by the fallbacks.
+func (c *Cloner) auths() []AuthOption {
+	return append([]AuthOption{{Method: c.authMethod, Auth: c.auth}}, c.fallbackAuths...)
+}
+
+// urlFor returns the clone URL of the project matching the auth method.
+func urlFor(method string, project *gitlab.Project) string {
+	if method == AuthMethodHTTPToken {
+		return project.HTTPURLToRepo
+	}
+
+	return project.SSHURLToRepo
+}
+
+// repoAuth returns the auth method matching the scheme of the origin URL,
+// a repo cloned by a fallback keeps using it on later runs.
+func (c *Cloner) repoAuth(repo *git.Repository) transport.AuthMethod {
+	remoteURL := originURL(repo)
+	if remoteURL == "" {
+		return c.auth
+	}
+
+	for _, option := range c.auths() {
+		if (option.Method == AuthMethodHTTPToken) == isHTTPURL(remoteURL) {
+			return option.Auth
+		}
+	}
+
+	return c.auth
+}
+
+// originURL returns the first URL of the origin remote, empty when the
+// repo has none.
+func originURL(repo *git.Repository) string {
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+
+	return remote.Config().URLs[0]
+}
+
+// cloneWithFallback clones with every auth method in turn until one is
+// not rejected by the server.
+func (c *Cloner) cloneWithFallback(ctx context.Context, log *slog.Logger, project *gitlab.Project, subPath string, cloneOptions *git.CloneOptions) (*git.Repository, error) {
+	auths := c.auths()
+
+	for i, option := range auths {
+		cloneOptions.URL = urlFor(option.Method, project)
+		cloneOptions.Auth = option.Auth
+		cloneOptions.ProxyOptions = c.proxyOptions(cloneOptions.URL)
+
+		var repo *git.Repository
+
+		err := c.retry(ctx, log, func() error {
+			var err error
+
+			repo, err = git.PlainCloneContext(ctx, subPath, c.bare, cloneOptions)
+
+			return err
+		})
+		if !isAuthError(err) || i == len(auths)-1 {
+			return repo, err
+		}
+
+		log.Warn("auth failed, trying next auth method",
+			slog.String("auth_method", option.Method),
+			slog.String("next_auth_method", auths[i+1].Method),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return nil, nil
+}
+
+func isAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(err.Error(), "unable to authenticate")
+}
@@ -0,0 +1,148 @@
+package cloner
+
+import (
+	"context"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestURLFor(t *testing.T) {
+	project := &gitlab.Project{
+		SSHURLToRepo:  "git@gitlab.com:acme/repo.git",
+		HTTPURLToRepo: "https://gitlab.com/acme/repo.git",
+	}
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: AuthMethodSSHAgent, want: project.SSHURLToRepo},
+		{method: AuthMethodSSHKey, want: project.SSHURLToRepo},
+		{method: AuthMethodHTTPToken, want: project.HTTPURLToRepo},
+	}
+
+	for _, tt := range tests {
+		if got := urlFor(tt.method, project); got != tt.want {
+			t.Errorf("urlFor(%s) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+// newHTTPRemote serves the repo in dir over smart HTTP through git
+// http-backend, requests without the username and password get a 401.
+func newHTTPRemote(t *testing.T, dir, username, password string) string {
+	t.Helper()
+
+	out, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skip("git not found")
+	}
+
+	backend := &cgi.Handler{
+		Path: filepath.Join(strings.TrimSpace(string(out)), "git-http-backend"),
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + filepath.Dir(dir),
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != username || pass != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/" + filepath.Base(dir)
+}
+
+func TestPullHTTPToken(t *testing.T) {
+	src := newSourceRepo(t)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		AuthMethod: AuthMethodHTTPToken,
+		Auth:       &githttp.BasicAuth{Username: "oauth2", Password: "secret"},
+	})
+
+	project := testProject(1, "acme/repo", newHTTPRemote(t, src, "oauth2", "secret"))
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, repo, src, "NEWS")
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(c.destDir, "acme", "repo", "NEWS")); err != nil {
+		t.Errorf("pulled file: %v", err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 1 || stats.Pulled != 1 || stats.Failed != 0 {
+		t.Errorf("cloned = %d, pulled = %d, failed = %d, want 1, 1 and 0",
+			stats.Cloned, stats.Pulled, stats.Failed)
+	}
+}
+
+func TestFallbackAuth(t *testing.T) {
+	src := newSourceRepo(t)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		AuthMethod: AuthMethodSSHAgent,
+		Auth:       &githttp.BasicAuth{Username: "oauth2", Password: "wrong"},
+		FallbackAuths: []AuthOption{
+			{Method: AuthMethodHTTPToken, Auth: &githttp.BasicAuth{Username: "oauth2", Password: "secret"}},
+		},
+	})
+
+	// The ssh URL points at the HTTP remote too, so the primary ssh agent
+	// method is rejected like a missing key would be, without a ssh server.
+	project := testProject(1, "acme/repo", newHTTPRemote(t, src, "oauth2", "secret"))
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, repo, src, "NEWS")
+
+	// The pull picks the fallback again from the HTTP origin URL.
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(c.destDir, "acme", "repo", "NEWS")); err != nil {
+		t.Errorf("pulled file: %v", err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 1 || stats.Pulled != 1 || stats.Failed != 0 {
+		t.Errorf("cloned = %d, pulled = %d, failed = %d, want 1, 1 and 0",
+			stats.Cloned, stats.Pulled, stats.Failed)
+	}
+}
@@ -0,0 +1,82 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/xanzy/go-gitlab"
+)
+
+// checkoutDefaultBranch switches the worktree to the project default
+// branch when HEAD is on another one, e.g. after master was renamed to
+// main on the server. The local branch is created to track origin when
+// missing, local changes are kept.
+func (c *Cloner) checkoutDefaultBranch(
+	ctx context.Context,
+	log *slog.Logger,
+	repo *git.Repository,
+	work *git.Worktree,
+	project *gitlab.Project,
+	depth int,
+) error {
+	branch := plumbing.NewBranchReferenceName(project.DefaultBranch)
+
+	head, err := repo.Head()
+	if err == nil && head.Name() == branch {
+		return nil
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      "origin",
+		Auth:            c.repoAuth(repo),
+		Progress:        c.progress,
+		Depth:           depth,
+		ProxyOptions:    c.repoProxyOptions(repo),
+		CABundle:        c.caBundle,
+		InsecureSkipTLS: c.insecureSkipTLS,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", project.DefaultBranch), true)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.Reference(branch, false)
+	create := errors.Is(err, plumbing.ErrReferenceNotFound)
+
+	if err != nil && !create {
+		return err
+	}
+
+	if create {
+		err = repo.CreateBranch(&config.Branch{
+			Name:   project.DefaultBranch,
+			Remote: "origin",
+			Merge:  branch,
+		})
+		if err != nil && !errors.Is(err, git.ErrBranchExists) {
+			return err
+		}
+	}
+
+	log.Info("checkout default branch", slog.String("branch", project.DefaultBranch))
+
+	checkout := &git.CheckoutOptions{
+		Branch: branch,
+		Create: create,
+		Keep:   true,
+	}
+
+	if create {
+		checkout.Hash = remoteRef.Hash()
+	}
+
+	return work.Checkout(checkout)
+}
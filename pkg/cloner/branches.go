@@ -0,0 +1,73 @@
+package cloner
+
+import (
+	"errors"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const remoteBranchPrefix = "refs/remotes/origin/"
+
+// trackBranches creates local tracking branches for the remote branches
+// matching the branch pattern and moves existing ones to the remote head.
+// The checked out branch is left to the pull.
+func (c *Cloner) trackBranches(log *slog.Logger, repo *git.Repository) error {
+	refs, err := repo.References()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	tracked := 0
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if ref.Type() != plumbing.HashReference || !strings.HasPrefix(name, remoteBranchPrefix) {
+			return nil
+		}
+
+		branch := strings.TrimPrefix(name, remoteBranchPrefix)
+
+		if ok, _ := path.Match(c.branchPattern, branch); !ok {
+			return nil
+		}
+
+		local := plumbing.NewBranchReferenceName(branch)
+		if local == head.Name() {
+			return nil
+		}
+
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(local, ref.Hash())); err != nil {
+			return err
+		}
+
+		err := repo.CreateBranch(&config.Branch{
+			Name:   branch,
+			Remote: "origin",
+			Merge:  local,
+		})
+		if err != nil && !errors.Is(err, git.ErrBranchExists) {
+			return err
+		}
+
+		tracked++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("tracking branches", slog.String("pattern", c.branchPattern), slog.Int("branches", tracked))
+
+	return nil
+}
@@ -0,0 +1,1386 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	AuthMethodSSHAgent  = "ssh-agent"
+	AuthMethodSSHKey    = "ssh-key"
+	AuthMethodHTTPToken = "http-token"
+)
+
+// Options configures a Cloner, zero values keep the defaults.
+type Options struct {
+	// Client is the GitLab API client, it is required.
+	Client *gitlab.Client
+
+	// Auth authenticates clones and pulls with the AuthMethod.
+	Auth transport.AuthMethod
+
+	// AuthMethod picks the clone URL, ssh for the ssh methods and https
+	// for http-token. It defaults to ssh-agent.
+	AuthMethod string
+
+	// DestDir is the directory repos are cloned into.
+	DestDir string
+
+	All              bool
+	Owned            bool
+	GroupIDs         []int
+	GroupPaths       []string
+	ProjectIDs       []int
+	IgnoreProjectIDs []int
+	IgnoreGroupIDs   []int
+
+	// Progress receives the git progress output, nil discards it.
+	Progress io.Writer
+
+	// ProgressBar receives the completed repo count, nil disables it.
+	ProgressBar io.Writer
+
+	Concurrency int
+
+	// Depth limits the fetched history, 0 fetches all of it.
+	Depth int
+
+	DryRun bool
+
+	// MaxRetries bounds the retries of transient clone and API errors,
+	// 0 tries once.
+	MaxRetries int
+
+	// CloneTimeout bounds the clone or pull of a single repo, 0 waits
+	// as long as it takes.
+	CloneTimeout time.Duration
+
+	IncludeArchived bool
+	IncludeRegex    string
+	ExcludeRegex    string
+	Branch          string
+	Bare            bool
+	LFS             bool
+	Prune           bool
+	PerPage         int
+
+	// PullStrategy is merge, rebase or ff-only, merge by default.
+	PullStrategy string
+
+	Visibility []string
+	SkipPull   bool
+
+	// MirrorTo is the base URL every repo is pushed to, under its
+	// namespace path.
+	MirrorTo string
+
+	// MirrorAuth authenticates the mirror pushes. It is never taken from
+	// Auth, the mirror is another host.
+	MirrorAuth transport.AuthMethod
+
+	// IncludeShared lists the projects shared into a group along with
+	// its own ones. The API lists them by default, the cloner does not.
+	IncludeShared bool
+
+	Ref               string
+	Topics            []string
+	RecurseSubmodules bool
+
+	// StateFile records the completed project IDs, a later run skips
+	// them unless ForceRefresh is set.
+	StateFile string
+
+	ForceRefresh    bool
+	ExcludePrefixes []string
+	Starred         bool
+	MaxRepoSizeMB   int
+
+	// PathTemplate lays out the repos below DestDir, DefaultPathTemplate
+	// when empty.
+	PathTemplate string
+
+	Verify       bool
+	Proxy        string
+	UpdatedAfter time.Time
+
+	// GroupDepths overrides Depth by group ID for the projects of the
+	// group and its subgroups, the closest group wins.
+	GroupDepths map[int]int
+
+	GC            bool
+	PostCloneHook string
+	Metrics       *Metrics
+	OnlyEmptyDir  bool
+	FetchOnly     bool
+
+	// MaxClonesPerHost bounds the concurrent clones of a single git
+	// host, 0 leaves it to Concurrency.
+	MaxClonesPerHost int
+
+	IgnoreGroupPaths []string
+
+	// Limit stops the enumeration after that many projects, 0 has no
+	// limit.
+	Limit int
+
+	RefSpecs []string
+
+	// GroupRootDir gives every top group its own root directory named
+	// by the group path, instead of DestDir. Shared projects go below the
+	// root of the group they were listed in.
+	GroupRootDir string
+
+	FailFast        bool
+	CABundle        []byte
+	InsecureSkipTLS bool
+	CloneOnlyNew    bool
+
+	// InMemory clones into memory only and passes every repo to Inspect.
+	InMemory bool
+
+	// Inspect is called with every repo cloned in memory, an error marks
+	// the project failed.
+	Inspect InspectFunc
+
+	TagsOnly        bool
+	CountOnly       bool
+	StashBeforePull bool
+	IncludeWikis    bool
+
+	// DirMode and Owner are applied to the cloned repos and their parent
+	// directories, zero values leave them as created.
+	DirMode os.FileMode
+	Owner   *Owner
+
+	NoForcePull bool
+
+	// MaxGroupDepth bounds the subgroup levels visited below a
+	// configured group, 0 visits the group alone. Nil visits all of them.
+	MaxGroupDepth *int
+
+	BranchPattern  string
+	RefreshRemotes bool
+
+	// FallbackAuths are tried in order when the server rejects the
+	// credentials of Auth on clone. Existing repos keep using the auth
+	// matching the scheme of their origin URL.
+	FallbackAuths []AuthOption
+
+	SparsePaths []string
+
+	// DedupByID appends the project ID to the path of projects colliding
+	// with one of a lower ID, otherwise the collision is only logged.
+	DedupByID bool
+}
+
+type Cloner struct {
+	destDir           string
+	client            *gitlab.Client
+	auth              transport.AuthMethod
+	authMethod        string
+	all               bool
+	owned             bool
+	groupIDs          []int
+	groupFullPaths    []string
+	projectIDs        []int
+	ignoreProjectIDs  []int
+	ignoreGroupIDs    []int
+	progress          io.Writer
+	concurrency       int
+	depth             int
+	dryRun            bool
+	maxRetries        int
+	cloneTimeout      time.Duration
+	includeArchived   bool
+	includeRegex      *regexp.Regexp
+	excludeRegex      *regexp.Regexp
+	branch            string
+	bare              bool
+	lfs               bool
+	prune             bool
+	progressBar       *progressBar
+	perPage           int
+	pullStrategy      string
+	visibility        []string
+	skipPull          bool
+	mirrorTo          string
+	mirrorAuth        transport.AuthMethod
+	includeShared     bool
+	ref               string
+	topics            []string
+	recurseSubmodules bool
+	stateFile         string
+	forceRefresh      bool
+	excludePrefixes   []string
+	starred           bool
+	maxRepoSizeMB     int
+	pathTemplate      *template.Template
+	verify            bool
+	proxy             string
+	updatedAfter      time.Time
+	groupDepths       map[int]int
+	groupPaths        map[string]int
+	gcRepo            bool
+	postCloneHookCmd  string
+	metrics           *Metrics
+	onlyEmptyDir      bool
+	fetchOnly         bool
+	maxClonesPerHost  int
+	hostSlots         map[string]chan struct{}
+	hostSlotsMu       sync.Mutex
+	ignoreGroupPaths  []string
+	limit             int
+	refSpecs          []config.RefSpec
+	groupRootDir      string
+	failFast          bool
+	cancel            context.CancelFunc
+	caBundle          []byte
+	insecureSkipTLS   bool
+	cloneOnlyNew      bool
+	inMemory          bool
+	inspect           InspectFunc
+	tagsOnly          bool
+	countOnly         bool
+	stashBeforePull   bool
+	includeWikis      bool
+	dirMode           os.FileMode
+	owner             *Owner
+	noForcePull       bool
+	maxGroupDepth     int
+	branchPattern     string
+	refreshRemotes    bool
+	fallbackAuths     []AuthOption
+	sparsePaths       []string
+	dedupByID         bool
+	completed         map[int]bool
+	stateMu           sync.Mutex
+	jobs              []cloneJob
+	queued            map[int]bool
+	suffixed          map[int]bool
+	queuedMu          sync.Mutex
+	visited           map[int]bool
+	groupsMu          sync.Mutex
+	stats             Stats
+	statsMu           sync.Mutex
+	results           []Result
+	seen              map[string]bool
+	seenMu            sync.Mutex
+}
+
+type cloneJob struct {
+	project *gitlab.Project
+	root    string
+	dest    string
+}
+
+func New(opts Options) (*Cloner, error) {
+	if opts.Client == nil {
+		return nil, errors.New("gitlab client is required")
+	}
+
+	c := &Cloner{
+		destDir:           opts.DestDir,
+		client:            opts.Client,
+		auth:              opts.Auth,
+		authMethod:        opts.AuthMethod,
+		all:               opts.All,
+		owned:             opts.Owned,
+		groupIDs:          opts.GroupIDs,
+		groupFullPaths:    opts.GroupPaths,
+		projectIDs:        opts.ProjectIDs,
+		ignoreProjectIDs:  opts.IgnoreProjectIDs,
+		ignoreGroupIDs:    opts.IgnoreGroupIDs,
+		progress:          opts.Progress,
+		concurrency:       opts.Concurrency,
+		depth:             opts.Depth,
+		dryRun:            opts.DryRun,
+		maxRetries:        opts.MaxRetries,
+		cloneTimeout:      opts.CloneTimeout,
+		includeArchived:   opts.IncludeArchived,
+		branch:            opts.Branch,
+		bare:              opts.Bare,
+		lfs:               opts.LFS,
+		prune:             opts.Prune,
+		perPage:           opts.PerPage,
+		pullStrategy:      opts.PullStrategy,
+		visibility:        opts.Visibility,
+		skipPull:          opts.SkipPull,
+		mirrorTo:          opts.MirrorTo,
+		mirrorAuth:        opts.MirrorAuth,
+		includeShared:     opts.IncludeShared,
+		ref:               opts.Ref,
+		topics:            opts.Topics,
+		recurseSubmodules: opts.RecurseSubmodules,
+		stateFile:         opts.StateFile,
+		forceRefresh:      opts.ForceRefresh,
+		excludePrefixes:   opts.ExcludePrefixes,
+		starred:           opts.Starred,
+		maxRepoSizeMB:     opts.MaxRepoSizeMB,
+		verify:            opts.Verify,
+		proxy:             opts.Proxy,
+		updatedAfter:      opts.UpdatedAfter,
+		groupDepths:       opts.GroupDepths,
+		groupPaths:        map[string]int{},
+		gcRepo:            opts.GC,
+		postCloneHookCmd:  opts.PostCloneHook,
+		metrics:           opts.Metrics,
+		onlyEmptyDir:      opts.OnlyEmptyDir,
+		fetchOnly:         opts.FetchOnly,
+		maxClonesPerHost:  opts.MaxClonesPerHost,
+		hostSlots:         map[string]chan struct{}{},
+		ignoreGroupPaths:  opts.IgnoreGroupPaths,
+		limit:             opts.Limit,
+		groupRootDir:      opts.GroupRootDir,
+		failFast:          opts.FailFast,
+		caBundle:          opts.CABundle,
+		insecureSkipTLS:   opts.InsecureSkipTLS,
+		cloneOnlyNew:      opts.CloneOnlyNew,
+		inMemory:          opts.InMemory,
+		inspect:           opts.Inspect,
+		tagsOnly:          opts.TagsOnly,
+		countOnly:         opts.CountOnly,
+		stashBeforePull:   opts.StashBeforePull,
+		includeWikis:      opts.IncludeWikis,
+		dirMode:           opts.DirMode,
+		owner:             opts.Owner,
+		noForcePull:       opts.NoForcePull,
+		maxGroupDepth:     -1,
+		branchPattern:     opts.BranchPattern,
+		refreshRemotes:    opts.RefreshRemotes,
+		fallbackAuths:     opts.FallbackAuths,
+		sparsePaths:       opts.SparsePaths,
+		dedupByID:         opts.DedupByID,
+		queued:            map[int]bool{},
+		suffixed:          map[int]bool{},
+		visited:           map[int]bool{},
+	}
+
+	if c.destDir == "" {
+		c.destDir = "./repos"
+	}
+
+	if c.authMethod == "" {
+		c.authMethod = AuthMethodSSHAgent
+	}
+
+	if c.progress == nil {
+		c.progress = io.Discard
+	}
+
+	if opts.ProgressBar != nil {
+		c.progressBar = &progressBar{
+			w: opts.ProgressBar,
+		}
+	}
+
+	if c.concurrency == 0 {
+		c.concurrency = 1
+	}
+
+	if c.perPage == 0 {
+		c.perPage = 100
+	}
+
+	if c.pullStrategy == "" {
+		c.pullStrategy = PullStrategyMerge
+	}
+
+	for _, option := range c.auths() {
+		switch option.Method {
+		case AuthMethodSSHAgent, AuthMethodSSHKey, AuthMethodHTTPToken:
+		default:
+			return nil, fmt.Errorf("unknown auth method %q", option.Method)
+		}
+	}
+
+	if c.concurrency < 1 {
+		return nil, fmt.Errorf("invalid concurrency %d", c.concurrency)
+	}
+
+	if c.depth < 0 {
+		return nil, fmt.Errorf("invalid depth %d", c.depth)
+	}
+
+	for _, refSpec := range opts.RefSpecs {
+		spec := config.RefSpec(refSpec)
+
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("refspec %q: %w", refSpec, err)
+		}
+
+		c.refSpecs = append(c.refSpecs, spec)
+	}
+
+	if c.groupRootDir != "" && c.prune {
+		return nil, errors.New("prune can not be used with a group root dir")
+	}
+
+	if c.prune {
+		if err := c.pruneConflict(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", c.limit)
+	}
+
+	if c.maxClonesPerHost < 0 {
+		return nil, fmt.Errorf("invalid max clones per host %d", c.maxClonesPerHost)
+	}
+
+	for groupID, depth := range c.groupDepths {
+		if depth < 0 {
+			return nil, fmt.Errorf("invalid depth %d for group %d", depth, groupID)
+		}
+	}
+
+	if c.perPage < 1 || c.perPage > 100 {
+		return nil, fmt.Errorf("invalid per page %d, must be between 1 and 100", c.perPage)
+	}
+
+	switch c.pullStrategy {
+	case PullStrategyMerge, PullStrategyRebase, PullStrategyFFOnly:
+	default:
+		return nil, fmt.Errorf("unknown pull strategy %q", c.pullStrategy)
+	}
+
+	for _, visibility := range c.visibility {
+		switch gitlab.VisibilityValue(visibility) {
+		case gitlab.PublicVisibility, gitlab.InternalVisibility, gitlab.PrivateVisibility:
+		default:
+			return nil, fmt.Errorf("unknown visibility %q", visibility)
+		}
+	}
+
+	if c.maxRepoSizeMB < 0 {
+		return nil, fmt.Errorf("invalid max repo size %d", c.maxRepoSizeMB)
+	}
+
+	if c.ref != "" && c.bare {
+		return nil, errors.New("ref checkout needs a worktree, it can not be used with bare")
+	}
+
+	if c.proxy != "" {
+		if err := validateProxy(c.proxy); err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+	}
+
+	if opts.MaxGroupDepth != nil {
+		if *opts.MaxGroupDepth < 0 {
+			return nil, fmt.Errorf("invalid max group depth %d", *opts.MaxGroupDepth)
+		}
+
+		c.maxGroupDepth = *opts.MaxGroupDepth
+	}
+
+	if c.branchPattern != "" {
+		if _, err := path.Match(c.branchPattern, ""); err != nil {
+			return nil, fmt.Errorf("branch pattern %q: %w", c.branchPattern, err)
+		}
+
+		if c.branch != "" || c.bare || c.tagsOnly {
+			return nil, errors.New("branch pattern can not be used with branch, bare or tags only")
+		}
+	}
+
+	if len(c.sparsePaths) > 0 && (c.bare || c.ref != "" || c.stashBeforePull || c.noForcePull) {
+		return nil, errors.New("sparse paths can not be used with bare, ref, stash before pull or no force pull")
+	}
+
+	if len(c.sparsePaths) > 0 || c.pullStrategy == PullStrategyRebase {
+		if name := c.binaryPullConflict(); name != "" {
+			return nil, fmt.Errorf("rebase and sparse pulls run the git binary, they can not be used with %s", name)
+		}
+	}
+
+	if c.includeWikis && c.stateFile != "" {
+		return nil, errors.New("wikis share the project id, they can not be used with a state file")
+	}
+
+	if c.stashBeforePull && c.bare {
+		return nil, errors.New("stash needs a worktree, it can not be used with bare")
+	}
+
+	if c.tagsOnly && (c.branch != "" || c.ref != "" || c.inMemory) {
+		return nil, errors.New("tags only can not be used with branch, ref or in memory")
+	}
+
+	if c.inMemory {
+		if name := c.inMemoryConflict(); name != "" {
+			return nil, fmt.Errorf("%s needs repos on disk, it can not be used in memory", name)
+		}
+	}
+
+	var err error
+
+	if opts.IncludeRegex != "" {
+		c.includeRegex, err = regexp.Compile(opts.IncludeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("include regex: %w", err)
+		}
+	}
+
+	if opts.ExcludeRegex != "" {
+		c.excludeRegex, err = regexp.Compile(opts.ExcludeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("exclude regex: %w", err)
+		}
+	}
+
+	if opts.PathTemplate == "" {
+		opts.PathTemplate = DefaultPathTemplate
+	}
+
+	c.pathTemplate, err = template.New("path").Option("missingkey=error").Parse(opts.PathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("path template: %w", err)
+	}
+
+	// Only the path tells a wiki apart from its project, they share the
+	// rest of the template data.
+	if c.includeWikis && !strings.Contains(opts.PathTemplate, ".Path") {
+		return nil, errors.New("path template must use .Path or .PathWithNamespace with wikis")
+	}
+
+	if c.stateFile != "" {
+		if err := c.loadState(); err != nil {
+			return nil, fmt.Errorf("state file: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// CheckAuth verifies the token by requesting the current user.
+func (c *Cloner) CheckAuth(ctx context.Context) error {
+	return c.retry(ctx, slog.Default(), func() error {
+		_, _, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+
+		return err
+	})
+}
+
+// Run enumerates the configured projects and groups, clones them and
+// prunes stale repos when requested. All failures are joined into the
+// returned error. Cancelling ctx stops starting new work, with fail fast
+// the first failure does the same.
+func (c *Cloner) Run(ctx context.Context) error {
+	if c.failFast {
+		ctx, c.cancel = context.WithCancel(ctx)
+		defer c.cancel()
+	}
+
+	errs := []error{}
+
+	if c.all {
+		errs = append(errs, c.All(ctx))
+	}
+
+	if c.starred {
+		errs = append(errs, c.Starred(ctx))
+	}
+
+	for _, gid := range c.groupIDs {
+		errs = append(errs, c.Group(ctx, gid))
+	}
+
+	for _, groupPath := range c.groupFullPaths {
+		gid, err := c.groupID(ctx, groupPath)
+		if err != nil || gid == 0 {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		errs = append(errs, c.Group(ctx, gid))
+	}
+
+	for _, pid := range c.projectIDs {
+		errs = append(errs, c.Project(ctx, pid))
+	}
+
+	if c.limitReached() {
+		slog.Info("limit reached", slog.Int("limit", c.limit))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		c.failed()
+	}
+
+	if c.countOnly {
+		c.logCount()
+
+		return errors.Join(errs...)
+	}
+
+	errs = append(errs, c.Clone(ctx))
+
+	if c.prune {
+		if err := errors.Join(errs...); err != nil {
+			slog.Warn("prune skipped", slog.Int("failed", c.Stats().Failed))
+		} else {
+			errs = append(errs, c.Prune())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Cloner) listOptions() gitlab.ListOptions {
+	return gitlab.ListOptions{
+		PerPage: c.perPage,
+		OrderBy: "name",
+		Sort:    "asc",
+	}
+}
+
+// groupID resolves the full path of a group to its ID, a missing group
+// is skipped and resolves to 0.
+func (c *Cloner) groupID(ctx context.Context, groupPath string) (int, error) {
+	log := slog.With(slog.String("group", groupPath))
+
+	var group *gitlab.Group
+
+	err := c.retry(ctx, log, func() error {
+		var err error
+
+		group, _, err = c.client.Groups.GetGroup(
+			groupPath,
+			&gitlab.GetGroupOptions{},
+			gitlab.WithContext(ctx),
+		)
+
+		return err
+	})
+	switch apiStatus(err) {
+	case http.StatusNotFound:
+		log.Warn("group not found")
+		c.inc(&c.stats.Skipped)
+
+		return 0, nil
+	case http.StatusForbidden:
+		log.Warn("no access to group", slog.String("error", err.Error()))
+		c.inc(&c.stats.NoAccess)
+
+		return 0, nil
+	}
+
+	if err != nil {
+		log.Error("get group error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return 0, err
+	}
+
+	return group.ID, nil
+}
+
+// Group enumerates the group and its subgroups down to the max group
+// depth. Subgroups are walked on up to concurrency goroutines, a subgroup
+// is walked inline when all of them are busy.
+func (c *Cloner) Group(ctx context.Context, groupID int) error {
+	var (
+		errs   []error
+		errsMu sync.Mutex
+	)
+
+	g := errgroup.Group{}
+	g.SetLimit(c.concurrency)
+
+	var walk func(groupID int, top *gitlab.Group, depth int)
+
+	walk = func(groupID int, top *gitlab.Group, depth int) {
+		group, subGroups, err := c.group(ctx, groupID, top, depth)
+		if err != nil {
+			c.failed()
+
+			errsMu.Lock()
+			errs = append(errs, err)
+			errsMu.Unlock()
+		}
+
+		if top == nil {
+			top = group
+		}
+
+		for _, subGroup := range subGroups {
+			next := func() error {
+				walk(subGroup.ID, top, depth+1)
+
+				return nil
+			}
+
+			if !g.TryGo(next) {
+				_ = next()
+			}
+		}
+	}
+
+	walk(groupID, nil, 0)
+
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+// group enumerates the projects of a single group and returns it with its
+// subgroups, top is the group the walk started from and depth the distance
+// from it.
+func (c *Cloner) group(ctx context.Context, groupID int, top *gitlab.Group, depth int) (*gitlab.Group, []*gitlab.Group, error) {
+	log := slog.With(slog.Int("group_id", groupID))
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if slices.Contains(c.ignoreGroupIDs, groupID) {
+		log.Warn("ignore group")
+
+		return nil, nil, nil
+	}
+
+	if c.limitReached() {
+		return nil, nil, nil
+	}
+
+	c.groupsMu.Lock()
+	visited := c.visited[groupID]
+	c.visited[groupID] = true
+	c.groupsMu.Unlock()
+
+	if visited {
+		log.Debug("skip visited group")
+
+		return nil, nil, nil
+	}
+
+	var group *gitlab.Group
+
+	err := c.retry(ctx, log, func() error {
+		var err error
+
+		group, _, err = c.client.Groups.GetGroup(
+			groupID,
+			&gitlab.GetGroupOptions{
+				ListOptions: c.listOptions(),
+			},
+			gitlab.WithContext(ctx),
+		)
+
+		return err
+	})
+	switch apiStatus(err) {
+	case http.StatusNotFound:
+		log.Warn("group not found")
+		c.inc(&c.stats.Skipped)
+
+		return nil, nil, nil
+	case http.StatusForbidden:
+		log.Warn("no access to group", slog.String("error", err.Error()))
+		c.inc(&c.stats.NoAccess)
+
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		log.Error("get group error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return nil, nil, fmt.Errorf("get group %d: %w", groupID, err)
+	}
+
+	log = log.With(slog.String("group", group.FullPath))
+
+	if slices.Contains(c.ignoreGroupPaths, group.FullPath) {
+		log.Warn("ignore group")
+
+		return nil, nil, nil
+	}
+
+	c.groupsMu.Lock()
+	c.groupPaths[group.FullPath] = group.ID
+	c.groupsMu.Unlock()
+
+	if top == nil {
+		top = group
+	}
+
+	log.Info("get group repos")
+
+	projects, err := c.listGroupProjects(ctx, group.ID)
+	if err != nil {
+		log.Error("list projects error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return nil, nil, fmt.Errorf("list group %s projects: %w", group.FullPath, err)
+	}
+
+	for _, project := range projects {
+		if c.limitReached() {
+			return nil, nil, nil
+		}
+
+		root, dest := c.groupDest(project, top)
+
+		if c.skipProject(project) {
+			c.keep(project, root, dest)
+
+			continue
+		}
+
+		c.enqueue(project, root, dest)
+	}
+
+	if c.maxGroupDepth >= 0 && depth >= c.maxGroupDepth {
+		log.Debug("max group depth reached")
+
+		return group, nil, nil
+	}
+
+	groups, err := c.listSubGroups(ctx, group.ID)
+	if err != nil {
+		log.Error("list subgroups error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return nil, nil, fmt.Errorf("list group %s subgroups: %w", group.FullPath, err)
+	}
+
+	return group, groups, nil
+}
+
+// failed stops the run on a failure when fail fast is enabled.
+func (c *Cloner) failed() {
+	if c.cancel != nil {
+		slog.Warn("fail fast, stopping run")
+
+		c.cancel()
+	}
+}
+
+// groupDest returns the root directory and namespace path a group project
+// is cloned into. With a group root dir every top group gets its own
+// root, named by the group path, holding the namespaces below it.
+func (c *Cloner) groupDest(project *gitlab.Project, top *gitlab.Group) (string, string) {
+	namespace := project.Namespace.FullPath
+
+	if c.groupRootDir == "" {
+		return c.destDir, namespace
+	}
+
+	root := filepath.Join(c.groupRootDir, top.Path)
+
+	switch {
+	case namespace == top.FullPath:
+		return root, "."
+	case strings.HasPrefix(namespace, top.FullPath+"/"):
+		return root, strings.TrimPrefix(namespace, top.FullPath+"/")
+	default:
+		return root, namespace
+	}
+}
+
+func (c *Cloner) listGroupProjects(ctx context.Context, groupID int) ([]*gitlab.Project, error) {
+	opts := &gitlab.ListGroupProjectsOptions{
+		ListOptions: c.listOptions(),
+	}
+
+	// The API includes projects shared into the group by default, they
+	// are only listed when asked for.
+	opts.WithShared = gitlab.Ptr(c.includeShared)
+
+	projects := []*gitlab.Project{}
+
+	log := slog.With(slog.Int("group_id", groupID))
+
+	for {
+		var (
+			page []*gitlab.Project
+			resp *gitlab.Response
+		)
+
+		err := c.retry(ctx, log, func() error {
+			var err error
+
+			page, resp, err = c.client.Groups.ListGroupProjects(groupID, opts, gitlab.WithContext(ctx), c.withStatistics())
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, page...)
+
+		if resp.NextPage == 0 {
+			return projects, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+func (c *Cloner) listSubGroups(ctx context.Context, groupID int) ([]*gitlab.Group, error) {
+	opts := &gitlab.ListSubGroupsOptions{
+		ListOptions: c.listOptions(),
+	}
+
+	groups := []*gitlab.Group{}
+
+	log := slog.With(slog.Int("group_id", groupID))
+
+	for {
+		var (
+			page []*gitlab.Group
+			resp *gitlab.Response
+		)
+
+		err := c.retry(ctx, log, func() error {
+			var err error
+
+			page, resp, err = c.client.Groups.ListSubGroups(groupID, opts, gitlab.WithContext(ctx))
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, page...)
+
+		if resp.NextPage == 0 {
+			return groups, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+func (c *Cloner) Project(ctx context.Context, projectID int) error {
+	log := slog.With(slog.Int("project_id", projectID))
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if slices.Contains(c.ignoreProjectIDs, projectID) {
+		log.Warn("ignore project")
+		c.inc(&c.stats.Skipped)
+
+		return nil
+	}
+
+	if c.limitReached() {
+		return nil
+	}
+
+	var project *gitlab.Project
+
+	err := c.retry(ctx, log, func() error {
+		var err error
+
+		project, _, err = c.client.Projects.GetProject(
+			projectID,
+			&gitlab.GetProjectOptions{Statistics: gitlab.Ptr(c.maxRepoSizeMB > 0)},
+			gitlab.WithContext(ctx),
+		)
+
+		return err
+	})
+	switch apiStatus(err) {
+	case http.StatusNotFound:
+		log.Warn("project not found")
+		c.inc(&c.stats.Skipped)
+
+		return nil
+	case http.StatusForbidden:
+		log.Warn("no access to project", slog.String("error", err.Error()))
+		c.inc(&c.stats.NoAccess)
+
+		return nil
+	}
+
+	if err != nil {
+		log.Error("get project error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return fmt.Errorf("get project %d: %w", projectID, err)
+	}
+
+	if c.tooLarge(project) {
+		c.inc(&c.stats.Skipped)
+		c.keep(project, c.destDir, project.Namespace.FullPath)
+
+		return nil
+	}
+
+	c.enqueue(project, c.destDir, project.Namespace.FullPath)
+
+	return nil
+}
+
+func (c *Cloner) All(ctx context.Context) error {
+	log := slog.Default()
+
+	log.Info("get all repos")
+
+	listOpts := &gitlab.ListProjectsOptions{
+		Membership: gitlab.Ptr(true),
+	}
+
+	if c.owned {
+		listOpts.Owned = gitlab.Ptr(true)
+	}
+
+	projects, err := c.listProjects(ctx, listOpts)
+	if err != nil {
+		log.Error("list projects error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return fmt.Errorf("list projects: %w", err)
+	}
+
+	c.enqueueProjects(log, projects)
+
+	return nil
+}
+
+func (c *Cloner) Starred(ctx context.Context) error {
+	log := slog.Default()
+
+	log.Info("get starred repos")
+
+	projects, err := c.listProjects(ctx, &gitlab.ListProjectsOptions{
+		Starred: gitlab.Ptr(true),
+	})
+	if err != nil {
+		log.Error("list starred projects error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return fmt.Errorf("list starred projects: %w", err)
+	}
+
+	c.enqueueProjects(log, projects)
+
+	return nil
+}
+
+func (c *Cloner) enqueueProjects(log *slog.Logger, projects []*gitlab.Project) {
+	for _, project := range projects {
+		if c.limitReached() {
+			return
+		}
+
+		if slices.Contains(c.ignoreProjectIDs, project.ID) {
+			log.Warn("ignore project", slog.Int("project_id", project.ID))
+			c.inc(&c.stats.Skipped)
+
+			continue
+		}
+
+		if c.skipProject(project) {
+			c.keep(project, c.destDir, project.Namespace.FullPath)
+
+			continue
+		}
+
+		c.enqueue(project, c.destDir, project.Namespace.FullPath)
+	}
+}
+
+func (c *Cloner) listProjects(ctx context.Context, opts *gitlab.ListProjectsOptions) ([]*gitlab.Project, error) {
+	opts.ListOptions = c.listOptions()
+	opts.Statistics = gitlab.Ptr(c.maxRepoSizeMB > 0)
+
+	if !c.updatedAfter.IsZero() {
+		opts.LastActivityAfter = gitlab.Ptr(c.updatedAfter)
+	}
+
+	projects := []*gitlab.Project{}
+
+	log := slog.Default()
+
+	for {
+		var (
+			page []*gitlab.Project
+			resp *gitlab.Response
+		)
+
+		err := c.retry(ctx, log, func() error {
+			var err error
+
+			page, resp, err = c.client.Projects.ListProjects(opts, gitlab.WithContext(ctx))
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, page...)
+
+		if resp.NextPage == 0 {
+			return projects, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// skipProject reports whether an enumerated project is filtered out.
+func (c *Cloner) skipProject(project *gitlab.Project) bool {
+	log := slog.With(slog.Int("project_id", project.ID), slog.String("path", project.PathWithNamespace))
+
+	if project.Archived && !c.includeArchived {
+		log.Debug("skip archived project")
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	if project.RepositoryAccessLevel == gitlab.DisabledAccessControl {
+		log.Debug("skip project with disabled repository")
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	if noCodeAccess(project) {
+		log.Warn("no access to project code", slog.Int("access_level", int(accessLevel(project))))
+		c.inc(&c.stats.NoAccess)
+
+		return true
+	}
+
+	if c.includeRegex != nil && !c.includeRegex.MatchString(project.PathWithNamespace) {
+		log.Debug("skip not included project")
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	if c.excludeRegex != nil && c.excludeRegex.MatchString(project.PathWithNamespace) {
+		log.Debug("skip excluded project")
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	if len(c.visibility) > 0 && !slices.Contains(c.visibility, string(project.Visibility)) {
+		log.Debug("skip project visibility", slog.String("visibility", string(project.Visibility)))
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	for _, prefix := range c.excludePrefixes {
+		if hasPathPrefix(project.PathWithNamespace, prefix) {
+			log.Debug("skip project path prefix", slog.String("prefix", prefix))
+			c.inc(&c.stats.Skipped)
+
+			return true
+		}
+	}
+
+	// Group project listings can not filter by activity, so the check
+	// also runs on the client.
+	if !c.updatedAfter.IsZero() && project.LastActivityAt != nil && project.LastActivityAt.Before(c.updatedAfter) {
+		log.Debug("skip inactive project", slog.Time("last_activity_at", *project.LastActivityAt))
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	for _, topic := range c.topics {
+		if !slices.Contains(project.Topics, topic) {
+			log.Debug("skip project topic", slog.String("topic", topic))
+			c.inc(&c.stats.Skipped)
+
+			return true
+		}
+	}
+
+	if c.tooLarge(project) {
+		c.inc(&c.stats.Skipped)
+
+		return true
+	}
+
+	return false
+}
+
+// withStatistics requests project statistics on group project listings,
+// the client options have no field for it.
+func (c *Cloner) withStatistics() gitlab.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		if c.maxRepoSizeMB == 0 {
+			return nil
+		}
+
+		query := req.URL.Query()
+		query.Set("statistics", "true")
+		req.URL.RawQuery = query.Encode()
+
+		return nil
+	}
+}
+
+// tooLarge reports whether the project repository exceeds the max repo
+// size. Statistics are only returned to members with at least reporter
+// access, projects without them are never skipped.
+func (c *Cloner) tooLarge(project *gitlab.Project) bool {
+	if c.maxRepoSizeMB == 0 {
+		return false
+	}
+
+	log := slog.With(slog.Int("project_id", project.ID), slog.String("path", project.PathWithNamespace))
+
+	if project.Statistics == nil {
+		log.Debug("no project statistics, size not checked")
+
+		return false
+	}
+
+	if project.Statistics.RepositorySize > int64(c.maxRepoSizeMB)*1024*1024 {
+		log.Warn("skip too large project",
+			slog.Int64("size", project.Statistics.RepositorySize),
+			slog.Int("max_size_mb", c.maxRepoSizeMB),
+		)
+
+		return true
+	}
+
+	return false
+}
+
+// hasPathPrefix reports whether the path is the prefix or below it, the
+// prefix only matches whole path segments.
+func hasPathPrefix(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// limitReached reports whether the limit of queued projects is hit, so
+// enumeration can stop early.
+func (c *Cloner) limitReached() bool {
+	c.queuedMu.Lock()
+	defer c.queuedMu.Unlock()
+
+	return c.limit > 0 && len(c.queued) >= c.limit
+}
+
+func (c *Cloner) enqueue(project *gitlab.Project, root, dest string) {
+	c.queuedMu.Lock()
+	defer c.queuedMu.Unlock()
+
+	if c.limit > 0 && len(c.queued) >= c.limit {
+		return
+	}
+
+	if c.queued[project.ID] {
+		slog.Debug("skip duplicate project", slog.Int("project_id", project.ID))
+
+		return
+	}
+
+	c.queued[project.ID] = true
+
+	c.jobs = append(c.jobs, cloneJob{
+		project: project,
+		root:    root,
+		dest:    dest,
+	})
+}
+
+// Clone runs the queued clones on a pool of concurrency workers and
+// returns the joined clone errors.
+func (c *Cloner) Clone(ctx context.Context) error {
+	c.resolveCollisions()
+
+	if c.progressBar != nil {
+		c.progressBar.start(len(c.jobs))
+	}
+
+	jobs := make(chan cloneJob, c.concurrency)
+
+	var (
+		errs   []error
+		errsMu sync.Mutex
+	)
+
+	wg := sync.WaitGroup{}
+
+	for range c.concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if err := c.gitClone(ctx, job.project, job.root, job.dest); err != nil {
+					c.failed()
+
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
+
+				if c.includeWikis && wikiEnabled(job.project) && ctx.Err() == nil {
+					if err := c.gitClone(ctx, wikiProject(job.project), job.root, job.dest); err != nil {
+						c.failed()
+
+						errsMu.Lock()
+						errs = append(errs, err)
+						errsMu.Unlock()
+					}
+				}
+
+				if c.progressBar != nil {
+					c.progressBar.inc()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, job := range c.jobs {
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+
+	wg.Wait()
+
+	c.jobs = nil
+
+	return errors.Join(append(errs, ctx.Err())...)
+}
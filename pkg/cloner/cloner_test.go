@@ -0,0 +1,714 @@
+package cloner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	os.Exit(m.Run())
+}
+
+// newTestCloner returns a cloner cloning into a temp dir, its client
+// talks to apiURL without retrying.
+func newTestCloner(t *testing.T, apiURL string, opts Options) *Cloner {
+	t.Helper()
+
+	client, err := gitlab.NewClient("token",
+		gitlab.WithBaseURL(apiURL+"/api/v4"),
+		gitlab.WithCustomRetryMax(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Client = client
+
+	if opts.DestDir == "" {
+		opts.DestDir = t.TempDir()
+	}
+
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// newSourceRepo creates a repo with a single commit to clone from and
+// returns its path.
+func newSourceRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("readme\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := work.Add("README"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = work.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func testProject(id int, pathWithNamespace, repoURL string) *gitlab.Project {
+	return &gitlab.Project{
+		ID:                id,
+		Path:              path.Base(pathWithNamespace),
+		PathWithNamespace: pathWithNamespace,
+		Namespace:         &gitlab.ProjectNamespace{FullPath: path.Dir(pathWithNamespace)},
+		SSHURLToRepo:      repoURL,
+		HTTPURLToRepo:     repoURL,
+	}
+}
+
+// fakeCommands replaces the command runner with one recording the
+// commands instead of running them, every binary is found unless listed
+// as missing.
+func fakeCommands(t *testing.T, missing ...string) *[]string {
+	t.Helper()
+
+	calls := []string{}
+
+	prevRunCommand, prevLookPath := runCommand, lookPath
+
+	runCommand = func(_ context.Context, _ string, _ []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+
+		return nil, nil
+	}
+
+	lookPath = func(file string) (string, error) {
+		for _, bin := range missing {
+			if bin == file {
+				return "", os.ErrNotExist
+			}
+		}
+
+		return file, nil
+	}
+
+	t.Cleanup(func() {
+		runCommand, lookPath = prevRunCommand, prevLookPath
+	})
+
+	return &calls
+}
+
+func testGroup(id, parentID int, fullPath string) *gitlab.Group {
+	return &gitlab.Group{
+		ID:       id,
+		ParentID: parentID,
+		Path:     path.Base(fullPath),
+		FullPath: fullPath,
+	}
+}
+
+// fakeGitLab serves the parts of the GitLab API the cloner uses from in
+// memory groups and projects, lists are paginated by per_page.
+type fakeGitLab struct {
+	groups   []*gitlab.Group
+	projects map[int][]*gitlab.Project
+	// status forces the response status of a request path like /groups/3.
+	status map[string]int
+	// flaky answers a request path with 500 as many times before serving
+	// it.
+	flaky map[string]int
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// start serves the fake API until the test ends and returns its URL.
+func (f *fakeGitLab) start(t *testing.T) string {
+	t.Helper()
+
+	f.hits = map[string]int{}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func (f *fakeGitLab) requests(p string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.hits[p]
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+
+	f.mu.Lock()
+	f.hits[p]++
+	flaky := f.hits[p] <= f.flaky[p]
+	f.mu.Unlock()
+
+	if flaky {
+		writeStatus(w, http.StatusInternalServerError)
+
+		return
+	}
+
+	if status, ok := f.status[p]; ok {
+		writeStatus(w, status)
+
+		return
+	}
+
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		parts[i], _ = url.PathUnescape(part)
+	}
+
+	switch {
+	case p == "/user":
+		writeJSON(w, &gitlab.User{ID: 1, Username: "test"})
+	case parts[0] == "groups" && len(parts) >= 2:
+		group := f.group(parts[1])
+		if group == nil {
+			writeStatus(w, http.StatusNotFound)
+
+			return
+		}
+
+		switch {
+		case len(parts) == 2:
+			writeJSON(w, group)
+		case parts[2] == "projects":
+			projects := f.projects[group.ID]
+
+			// Projects of other namespaces are shared into the group.
+			if r.URL.Query().Get("with_shared") == "false" {
+				projects = slices.DeleteFunc(slices.Clone(projects), func(project *gitlab.Project) bool {
+					return project.Namespace.FullPath != group.FullPath
+				})
+			}
+
+			paginate(w, r, projects)
+		case parts[2] == "subgroups":
+			paginate(w, r, slices.DeleteFunc(slices.Clone(f.groups), func(sub *gitlab.Group) bool {
+				return sub.ParentID != group.ID
+			}))
+		default:
+			writeStatus(w, http.StatusNotFound)
+		}
+	case parts[0] == "projects" && len(parts) == 2:
+		for _, projects := range f.projects {
+			for _, project := range projects {
+				if strconv.Itoa(project.ID) == parts[1] {
+					writeJSON(w, project)
+
+					return
+				}
+			}
+		}
+
+		writeStatus(w, http.StatusNotFound)
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitLab) group(id string) *gitlab.Group {
+	for _, group := range f.groups {
+		if strconv.Itoa(group.ID) == id || group.FullPath == id {
+			return group
+		}
+	}
+
+	return nil
+}
+
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage == 0 {
+		perPage = 20
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+
+	if end < len(items) {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+
+	writeJSON(w, append([]T{}, items[start:end]...))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeStatus(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"message": strconv.Itoa(status) + " " + http.StatusText(status),
+	})
+}
+
+// queuedIDs returns the sorted IDs of the queued projects.
+func queuedIDs(c *Cloner) []int {
+	ids := []int{}
+
+	for _, job := range c.jobs {
+		ids = append(ids, job.project.ID)
+	}
+
+	slices.Sort(ids)
+
+	return ids
+}
+
+func TestGroupPagination(t *testing.T) {
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{
+			testGroup(1, 0, "acme"),
+			testGroup(2, 1, "acme/team"),
+			testGroup(3, 1, "acme/ops"),
+		},
+		projects: map[int][]*gitlab.Project{
+			1: {
+				testProject(11, "acme/a", ""),
+				testProject(12, "acme/b", ""),
+				testProject(13, "acme/c", ""),
+			},
+			2: {testProject(21, "acme/team/d", "")},
+		},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{PerPage: 1})
+
+	if err := c.Group(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := queuedIDs(c), []int{11, 12, 13, 21}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+
+	if got := api.requests("/groups/1/projects"); got != 3 {
+		t.Errorf("project pages = %d, want 3", got)
+	}
+
+	if got := api.requests("/groups/1/subgroups"); got != 2 {
+		t.Errorf("subgroup pages = %d, want 2", got)
+	}
+}
+
+func TestGroupVisitedAndDedup(t *testing.T) {
+	shared := testProject(11, "acme/shared", "")
+
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{
+			testGroup(1, 0, "acme"),
+			testGroup(2, 1, "acme/team"),
+		},
+		projects: map[int][]*gitlab.Project{
+			1: {shared},
+			2: {shared, testProject(21, "acme/team/d", "")},
+		},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:  []int{1, 2},
+		CountOnly: true,
+	})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := queuedIDs(c), []int{11, 21}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+
+	if got := api.requests("/groups/2/projects"); got != 1 {
+		t.Errorf("subgroup listed %d times, want 1", got)
+	}
+}
+
+func TestIncludeShared(t *testing.T) {
+	shared := testProject(31, "other/lib", "")
+
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{
+			testGroup(1, 0, "acme"),
+			testGroup(2, 0, "ops"),
+		},
+		projects: map[int][]*gitlab.Project{
+			1: {testProject(11, "acme/a", ""), shared},
+			2: {testProject(21, "ops/b", ""), shared},
+		},
+	}
+
+	for _, tt := range []struct {
+		includeShared bool
+		want          []int
+	}{
+		{includeShared: false, want: []int{11, 21}},
+		{includeShared: true, want: []int{11, 21, 31}},
+	} {
+		c := newTestCloner(t, api.start(t), Options{
+			GroupIDs:      []int{1, 2},
+			IncludeShared: tt.includeShared,
+			CountOnly:     true,
+		})
+
+		if err := c.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := queuedIDs(c); !slices.Equal(got, tt.want) {
+			t.Errorf("include shared %t: queued = %v, want %v", tt.includeShared, got, tt.want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{testGroup(1, 0, "acme")},
+		projects: map[int][]*gitlab.Project{
+			1: {
+				testProject(11, "acme/a", ""),
+				testProject(12, "acme/b", ""),
+				testProject(13, "acme/c", ""),
+			},
+		},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:   []int{1},
+		ProjectIDs: []int{13},
+		Limit:      2,
+		CountOnly:  true,
+	})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := queuedIDs(c), []int{11, 12}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+
+	if got := api.requests("/projects/13"); got != 0 {
+		t.Errorf("project requested %d times after the limit, want 0", got)
+	}
+}
+
+func TestNotFoundAndForbidden(t *testing.T) {
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{
+			testGroup(1, 0, "acme"),
+			testGroup(3, 0, "secret"),
+		},
+		projects: map[int][]*gitlab.Project{
+			1: {
+				testProject(11, "acme/a", ""),
+				testProject(12, "acme/b", ""),
+			},
+		},
+		status: map[string]int{
+			"/groups/3":    http.StatusForbidden,
+			"/projects/12": http.StatusForbidden,
+		},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:   []int{2, 3},
+		ProjectIDs: []int{11, 12, 99},
+		CountOnly:  true,
+	})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := queuedIDs(c), []int{11}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+
+	stats := c.Stats()
+
+	if stats.Skipped != 2 || stats.NoAccess != 2 || stats.Failed != 0 {
+		t.Errorf("skipped = %d, no access = %d, failed = %d, want 2, 2 and 0",
+			stats.Skipped, stats.NoAccess, stats.Failed)
+	}
+}
+
+func TestGroupDest(t *testing.T) {
+	top := testGroup(1, 0, "acme/platform")
+
+	tests := []struct {
+		name         string
+		groupRootDir string
+		namespace    string
+		wantRoot     string
+		wantDest     string
+	}{
+		{name: "dest dir", namespace: "acme/platform/team", wantRoot: "dest", wantDest: "acme/platform/team"},
+		{name: "top group", groupRootDir: "roots", namespace: "acme/platform", wantRoot: "roots/platform", wantDest: "."},
+		{name: "subgroup", groupRootDir: "roots", namespace: "acme/platform/team/ops", wantRoot: "roots/platform", wantDest: "team/ops"},
+		{name: "shared", groupRootDir: "roots", namespace: "other", wantRoot: "roots/platform", wantDest: "other"},
+		{name: "prefix only", groupRootDir: "roots", namespace: "acme/platform-legacy", wantRoot: "roots/platform", wantDest: "acme/platform-legacy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cloner{destDir: "dest", groupRootDir: tt.groupRootDir}
+
+			project := testProject(1, tt.namespace+"/repo", "")
+
+			root, dest := c.groupDest(project, top)
+			if root != tt.wantRoot || dest != tt.wantDest {
+				t.Errorf("groupDest = %q, %q, want %q, %q", root, dest, tt.wantRoot, tt.wantDest)
+			}
+		})
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{path: "acme/legacy/repo", prefix: "acme/legacy", want: true},
+		{path: "acme/legacy/repo", prefix: "acme/legacy/", want: true},
+		{path: "acme/legacy", prefix: "acme/legacy", want: true},
+		{path: "acme/legacy-tools/repo", prefix: "acme/legacy", want: false},
+		{path: "acme/repo", prefix: "acme/legacy", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hasPathPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("hasPathPrefix(%q, %q) = %t, want %t", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+// sourceGroup returns a fake API with the group acme holding n projects,
+// with IDs from 1, all cloned from src.
+func sourceGroup(n int, src string) *fakeGitLab {
+	projects := []*gitlab.Project{}
+
+	for i := 1; i <= n; i++ {
+		projects = append(projects, testProject(i, "acme/repo-"+strconv.Itoa(i), src))
+	}
+
+	return &fakeGitLab{
+		groups:   []*gitlab.Group{testGroup(1, 0, "acme")},
+		projects: map[int][]*gitlab.Project{1: projects},
+	}
+}
+
+// concurrencyProbe returns an inspect func holding every repo for a
+// moment and the highest number of repos it saw at once.
+func concurrencyProbe() (InspectFunc, *atomic.Int32) {
+	var running, highest atomic.Int32
+
+	inspect := func(context.Context, *gitlab.Project, *git.Repository) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			seen := highest.Load()
+			if n <= seen || highest.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		return nil
+	}
+
+	return inspect, &highest
+}
+
+func TestConcurrency(t *testing.T) {
+	inspect, highest := concurrencyProbe()
+
+	api := sourceGroup(9, newSourceRepo(t))
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:    []int{1},
+		Concurrency: 3,
+		InMemory:    true,
+		Inspect:     inspect,
+	})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Stats().Cloned; got != 9 {
+		t.Errorf("cloned = %d, want 9", got)
+	}
+
+	if got := highest.Load(); got < 2 || got > 3 {
+		t.Errorf("concurrent clones = %d, want 2 or 3", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	api := sourceGroup(2, newSourceRepo(t))
+	apiURL := api.start(t)
+
+	c := newTestCloner(t, apiURL, Options{GroupIDs: []int{1}, DryRun: true})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(c.destDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("dest dir entries = %v, %v, want none", entries, err)
+	}
+
+	actions := map[int]string{}
+
+	for _, result := range c.Results() {
+		actions[result.ProjectID] = result.Action
+	}
+
+	if want := map[int]string{1: ActionClone, 2: ActionClone}; !maps.Equal(actions, want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+
+	if stats := c.Stats(); stats.Skipped != 2 || stats.Cloned != 0 {
+		t.Errorf("skipped = %d, cloned = %d, want 2 and 0", stats.Skipped, stats.Cloned)
+	}
+}
+
+func TestFailureSummary(t *testing.T) {
+	api := sourceGroup(3, newSourceRepo(t))
+	api.projects[1][1].SSHURLToRepo = filepath.Join(t.TempDir(), "missing")
+
+	c := newTestCloner(t, api.start(t), Options{GroupIDs: []int{1}})
+
+	err := c.Run(context.Background())
+
+	var cloneErr *CloneError
+	if !errors.As(err, &cloneErr) || cloneErr.ProjectID != 2 {
+		t.Errorf("Run error = %v, want a clone error of project 2", err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 2 || stats.Failed != 1 {
+		t.Errorf("cloned = %d, failed = %d, want 2 and 1", stats.Cloned, stats.Failed)
+	}
+
+	for _, result := range c.Results() {
+		want := StatusCloned
+		if result.ProjectID == 2 {
+			want = StatusFailed
+		}
+
+		if result.Status != want {
+			t.Errorf("project %d status = %s, want %s", result.ProjectID, result.Status, want)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	api := sourceGroup(5, newSourceRepo(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:    []int{1},
+		Concurrency: 1,
+		InMemory:    true,
+		Inspect: func(context.Context, *gitlab.Project, *git.Repository) error {
+			cancel()
+
+			return nil
+		},
+	})
+
+	if err := c.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run error = %v, want %v", err, context.Canceled)
+	}
+
+	if got := c.Stats().Cloned; got != 1 {
+		t.Errorf("cloned = %d, want 1", got)
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	api := sourceGroup(4, newSourceRepo(t))
+	api.projects[1][0].SSHURLToRepo = filepath.Join(t.TempDir(), "missing")
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:    []int{1},
+		Concurrency: 1,
+		FailFast:    true,
+	})
+
+	err := c.Run(context.Background())
+
+	var cloneErr *CloneError
+	if !errors.As(err, &cloneErr) || cloneErr.ProjectID != 1 {
+		t.Errorf("Run error = %v, want a clone error of project 1", err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 0 || stats.Failed != 1 {
+		t.Errorf("cloned = %d, failed = %d, want 0 and 1", stats.Cloned, stats.Failed)
+	}
+}
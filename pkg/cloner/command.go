@@ -0,0 +1,28 @@
+package cloner
+
+import (
+	"context"
+	"os/exec"
+)
+
+// runCommand runs an external command inside dir and returns its combined
+// output. A nil env keeps the environment of the process.
+var runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	return cmd.CombinedOutput()
+}
+
+// runShell runs command with sh inside dir using env and returns its
+// combined output.
+func runShell(ctx context.Context, dir string, env []string, command string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	return cmd.CombinedOutput()
+}
+
+var lookPath = exec.LookPath
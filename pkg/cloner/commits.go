@@ -0,0 +1,44 @@
+package cloner
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// commitsBetween counts the commits reachable from to until from is hit.
+// It reports false when from is not an ancestor of to, e.g. after a force
+// update, or when the history is cut by a shallow clone.
+func commitsBetween(repo *git.Repository, from, to plumbing.Hash) (int, bool) {
+	if from == to {
+		return 0, true
+	}
+
+	iter, err := repo.Log(&git.LogOptions{From: to})
+	if err != nil {
+		return 0, false
+	}
+
+	defer iter.Close()
+
+	count := 0
+	found := false
+
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if commit.Hash == from {
+			found = true
+
+			return storer.ErrStop
+		}
+
+		count++
+
+		return nil
+	})
+	if err != nil || !found {
+		return 0, false
+	}
+
+	return count, true
+}
@@ -0,0 +1,78 @@
+package cloner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFile writes the file in the repo worktree and commits it.
+func commitFile(t *testing.T, repo *git.Repository, dir, name string) plumbing.Hash {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := work.Add(name); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := work.Commit(name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestCommitsBetween(t *testing.T) {
+	dir := newSourceRepo(t)
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := head.Hash()
+	commitFile(t, repo, dir, "a")
+	last := commitFile(t, repo, dir, "b")
+
+	tests := []struct {
+		name     string
+		from, to plumbing.Hash
+		want     int
+		wantOK   bool
+	}{
+		{name: "same", from: last, to: last, want: 0, wantOK: true},
+		{name: "two new", from: first, to: last, want: 2, wantOK: true},
+		{name: "not an ancestor", from: last, to: first, wantOK: false},
+		{name: "unknown", from: plumbing.NewHash("1234"), to: last, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := commitsBetween(repo, tt.from, tt.to)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("commitsBetween = %d, %t, want %d, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package cloner
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// logCount logs the number of queued projects per namespace and in total.
+func (c *Cloner) logCount() {
+	counts := map[string]int{}
+
+	for _, job := range c.jobs {
+		namespace := ""
+		if job.project.Namespace != nil {
+			namespace = job.project.Namespace.FullPath
+		}
+
+		counts[namespace]++
+	}
+
+	namespaces := make([]string, 0, len(counts))
+	for namespace := range counts {
+		namespaces = append(namespaces, namespace)
+	}
+
+	slices.Sort(namespaces)
+
+	for _, namespace := range namespaces {
+		slog.Info("count",
+			slog.String("namespace", namespace),
+			slog.Int("projects", counts[namespace]),
+		)
+	}
+
+	slog.Info("count total", slog.Int("projects", len(c.jobs)))
+}
@@ -0,0 +1,54 @@
+package cloner
+
+import (
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+)
+
+// resolveCollisions looks for queued projects rendering to the same path
+// once enumeration is done. The project with the lowest ID keeps the path
+// whatever order the projects were enqueued in, with dedup by ID the
+// others get their ID appended, otherwise the collision is only logged.
+func (c *Cloner) resolveCollisions() {
+	owners := map[string][]int{}
+
+	for _, job := range c.jobs {
+		subPath, err := c.repoPath(job.project, job.dest)
+		if err != nil {
+			// Reported by the clone.
+			continue
+		}
+
+		subPath = path.Join(job.root, subPath)
+		owners[subPath] = append(owners[subPath], job.project.ID)
+	}
+
+	for _, subPath := range slices.Sorted(maps.Keys(owners)) {
+		ids := owners[subPath]
+		if len(ids) < 2 {
+			continue
+		}
+
+		slices.Sort(ids)
+
+		for _, id := range ids[1:] {
+			log := slog.With(
+				slog.Int("project_id", id),
+				slog.String("path", subPath),
+				slog.Int("owner_id", ids[0]),
+			)
+
+			if !c.dedupByID {
+				log.Warn("path collision")
+
+				continue
+			}
+
+			c.suffixed[id] = true
+
+			log.Warn("path collision, appending project id")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestResolveCollisions(t *testing.T) {
+	tests := []struct {
+		name      string
+		dedupByID bool
+		order     []int
+		want      map[int]bool
+	}{
+		{name: "log only", order: []int{1, 2, 3}, want: map[int]bool{}},
+		{name: "dedup", dedupByID: true, order: []int{1, 2, 3}, want: map[int]bool{2: true}},
+		{name: "dedup reversed", dedupByID: true, order: []int{3, 2, 1}, want: map[int]bool{2: true}},
+	}
+
+	projects := map[int]string{
+		1: "acme/repo",
+		2: "other/repo",
+		3: "acme/unique",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCloner(t, "http://127.0.0.1", Options{
+				PathTemplate: "{{.Path}}",
+				DedupByID:    tt.dedupByID,
+			})
+
+			for _, id := range tt.order {
+				project := testProject(id, projects[id], "")
+				c.enqueue(project, c.destDir, project.Namespace.FullPath)
+			}
+
+			c.resolveCollisions()
+
+			for id := range projects {
+				if c.suffixed[id] != tt.want[id] {
+					t.Errorf("project %d suffixed = %t, want %t", id, c.suffixed[id], tt.want[id])
+				}
+			}
+		})
+	}
+}
+
+func TestDedupByIDClone(t *testing.T) {
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		PathTemplate: "{{.Path}}",
+		DedupByID:    true,
+	})
+
+	srcA, srcB := newSourceRepo(t), newSourceRepo(t)
+
+	// Enqueued highest ID first, the lowest ID still keeps the path.
+	for _, project := range []*gitlab.Project{
+		testProject(2, "other/repo", srcB),
+		testProject(1, "acme/repo", srcA),
+	} {
+		c.enqueue(project, c.destDir, project.Namespace.FullPath)
+	}
+
+	if err := c.Clone(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"repo":   srcA,
+		"repo-2": srcB,
+	}
+
+	entries, err := os.ReadDir(c.destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	if !slices.Equal(names, []string{"repo", "repo-2"}) {
+		t.Fatalf("dirs = %q, want repo and repo-2", names)
+	}
+
+	for dir, src := range want {
+		repo, err := git.PlainOpen(filepath.Join(c.destDir, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := originURL(repo); got != src {
+			t.Errorf("%s origin = %q, want %q", dir, got, src)
+		}
+	}
+}
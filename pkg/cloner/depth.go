@@ -0,0 +1,29 @@
+package cloner
+
+import (
+	"path"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// projectDepth returns the clone depth for the project. A depth override
+// of its namespace group or of the closest visited parent group wins over
+// the global depth.
+func (c *Cloner) projectDepth(project *gitlab.Project) int {
+	if depth, ok := c.groupDepths[project.Namespace.ID]; ok {
+		return depth
+	}
+
+	for fullPath := project.Namespace.FullPath; fullPath != "." && fullPath != "/"; fullPath = path.Dir(fullPath) {
+		groupID, ok := c.groupPaths[fullPath]
+		if !ok {
+			continue
+		}
+
+		if depth, ok := c.groupDepths[groupID]; ok {
+			return depth
+		}
+	}
+
+	return c.depth
+}
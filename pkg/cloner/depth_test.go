@@ -0,0 +1,45 @@
+package cloner
+
+import (
+	"testing"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestProjectDepth(t *testing.T) {
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		Depth: 1,
+		GroupDepths: map[int]int{
+			1: 10,
+			3: 0,
+		},
+	})
+
+	c.groupPaths = map[string]int{
+		"acme":          1,
+		"acme/team":     2,
+		"acme/team/ops": 3,
+	}
+
+	tests := []struct {
+		namespaceID int
+		namespace   string
+		want        int
+	}{
+		{namespaceID: 1, namespace: "acme", want: 10},
+		{namespaceID: 2, namespace: "acme/team", want: 10},
+		{namespaceID: 3, namespace: "acme/team/ops", want: 0},
+		{namespaceID: 4, namespace: "acme/team/ops/deep", want: 0},
+		{namespaceID: 5, namespace: "other", want: 1},
+	}
+
+	for _, tt := range tests {
+		project := &gitlab.Project{
+			Namespace: &gitlab.ProjectNamespace{ID: tt.namespaceID, FullPath: tt.namespace},
+		}
+
+		if got := c.projectDepth(project); got != tt.want {
+			t.Errorf("projectDepth(%s) = %d, want %d", tt.namespace, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package cloner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// checkEmptyDir refuses a subPath that already holds files but no git
+// repo, which usually means the destination directory is misconfigured.
+func checkEmptyDir(subPath string) error {
+	entries, err := os.ReadDir(subPath)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = git.PlainOpen(subPath)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return fmt.Errorf("directory %s is not empty and not a git repo", subPath)
+	}
+
+	return err
+}
@@ -0,0 +1,31 @@
+package cloner
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+)
+
+// initEmpty initializes a local repo with the origin remote for a project
+// without commits, which go-git can not clone. An existing repo is kept.
+func (c *Cloner) initEmpty(subPath, repoURL string) error {
+	repo, err := git.PlainInit(subPath, c.bare)
+	if errors.Is(err, git.ErrRepositoryAlreadyExists) {
+		repo, err = git.PlainOpen(subPath)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+	if err != nil && !errors.Is(err, git.ErrRemoteExists) {
+		return err
+	}
+
+	return nil
+}
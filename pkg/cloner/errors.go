@@ -0,0 +1,49 @@
+package cloner
+
+import "fmt"
+
+// CloneError is returned when the initial clone of a project fails.
+type CloneError struct {
+	ProjectID int
+	Path      string
+	Err       error
+}
+
+func (e *CloneError) Error() string {
+	return fmt.Sprintf("clone repo %s: %v", e.Path, e.Err)
+}
+
+func (e *CloneError) Unwrap() error {
+	return e.Err
+}
+
+// PullError is returned when updating an existing clone fails, either by
+// pull or, for bare repos, by fetch.
+type PullError struct {
+	ProjectID int
+	Path      string
+	Err       error
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("pull repo %s: %v", e.Path, e.Err)
+}
+
+func (e *PullError) Unwrap() error {
+	return e.Err
+}
+
+// OpenError is returned when an existing clone can not be opened.
+type OpenError struct {
+	ProjectID int
+	Path      string
+	Err       error
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("open repo %s: %v", e.Path, e.Err)
+}
+
+func (e *OpenError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,25 @@
+package cloner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// gc repacks the repo with the git binary, go-git has no equivalent. A
+// missing binary is reported and skipped.
+func (c *Cloner) gc(ctx context.Context, log *slog.Logger, subPath string) error {
+	if _, err := lookPath("git"); err != nil {
+		log.Warn("gc skipped", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	out, err := runCommand(ctx, subPath, nil, "git", "gc", "--aggressive", "--prune=now", "--quiet")
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
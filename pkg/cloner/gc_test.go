@@ -0,0 +1,46 @@
+package cloner
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestGC(t *testing.T) {
+	const gcCall = "git gc --aggressive --prune=now --quiet"
+
+	tests := []struct {
+		name    string
+		gc      bool
+		missing []string
+		want    int
+	}{
+		{name: "disabled"},
+		{name: "enabled", gc: true, want: 2},
+		{name: "missing git", gc: true, missing: []string{"git"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeCommands(t, tt.missing...)
+
+			c := newTestCloner(t, "http://127.0.0.1", Options{GC: tt.gc})
+
+			project := testProject(1, "acme/repo", newSourceRepo(t))
+
+			// The second run pulls the existing clone, gc runs after both.
+			for range 2 {
+				if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := len(slices.DeleteFunc(slices.Clone(*calls), func(call string) bool {
+				return call != gcCall
+			}))
+			if got != tt.want {
+				t.Errorf("gc calls = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
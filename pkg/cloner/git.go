@@ -0,0 +1,528 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+)
+
+func (c *Cloner) gitClone(ctx context.Context, project *gitlab.Project, root, dest string) error {
+	log := slog.With(slog.Int("project_id", project.ID))
+
+	result := newResult(project)
+
+	subPath, err := c.repoPath(project, dest)
+	if err != nil {
+		log.Error("path template error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return fmt.Errorf("path template %s: %w", project.PathWithNamespace, err)
+	}
+
+	log = log.With(slog.String("path", subPath))
+
+	log.Info("get repo")
+
+	subPath = path.Join(root, subPath)
+	repoURL := c.repoURL(project)
+
+	c.markSeen(subPath)
+
+	if c.stateFile != "" && !c.forceRefresh && c.isCompleted(project.ID) {
+		log.Info("already completed, skipping")
+		c.record(result, StatusSkipped)
+
+		return nil
+	}
+
+	if c.dryRun {
+		result.Action = plannedAction(subPath)
+
+		log.Info("dry run",
+			slog.String("action", result.Action),
+			slog.String("dest", subPath),
+			slog.String("url", repoURL),
+		)
+		c.record(result, StatusSkipped)
+
+		return nil
+	}
+
+	// Waiting for a host slot does not count towards the clone timeout.
+	release, err := c.acquireHost(ctx, repoURL)
+	if err != nil {
+		c.record(result, StatusSkipped)
+
+		return nil
+	}
+
+	defer release()
+
+	if c.cloneTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.cloneTimeout)
+		defer cancel()
+	}
+
+	if c.onlyEmptyDir {
+		if err := checkEmptyDir(subPath); err != nil {
+			log.Error("dest dir error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("dest dir %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	if c.cloneOnlyNew {
+		if _, err := os.Stat(subPath); err == nil {
+			log.Info("already exists, skipping")
+			c.record(result, StatusSkipped)
+
+			return nil
+		}
+	}
+
+	depth := c.projectDepth(project)
+
+	cloneOptions := &git.CloneOptions{
+		URL:             repoURL,
+		Auth:            c.auth,
+		Progress:        c.progress,
+		Depth:           depth,
+		ProxyOptions:    c.proxyOptions(repoURL),
+		CABundle:        c.caBundle,
+		InsecureSkipTLS: c.insecureSkipTLS,
+		NoCheckout:      len(c.sparsePaths) > 0,
+	}
+
+	pullOptions := c.pullOptions(depth)
+
+	if c.branch != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(c.branch)
+		cloneOptions.SingleBranch = true
+	}
+
+	if c.inMemory {
+		return c.memoryClone(ctx, log, result, project, cloneOptions)
+	}
+
+	if project.EmptyRepo {
+		return c.gitEmpty(log, result, project, root, subPath, repoURL)
+	}
+
+	if c.tagsOnly {
+		return c.tagsClone(ctx, log, result, project, root, subPath, repoURL, depth)
+	}
+
+	repo, err := c.cloneWithFallback(ctx, log, project, subPath, cloneOptions)
+	if c.branch != "" && isBranchNotFound(err) {
+		log.Warn("branch not found", slog.String("branch", c.branch))
+		c.record(result, StatusSkipped)
+
+		return nil
+	}
+
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return c.gitEmpty(log, result, project, root, subPath, repoURL)
+	}
+
+	if err != nil && isNoAccess(err) {
+		log.Warn("no access to repo", slog.String("error", err.Error()))
+		c.record(result, StatusNoAccess)
+
+		return nil
+	}
+
+	if err != nil && !errors.Is(err, git.ErrRepositoryAlreadyExists) {
+		log.Error("clone repo error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return &CloneError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+	}
+
+	cloned := err == nil
+
+	if !cloned && c.skipPull {
+		log.Info("already exists, skipping pull")
+		c.record(result, StatusSkipped)
+
+		return nil
+	}
+
+	if !cloned {
+		err = c.retry(ctx, log, func() error {
+			var err error
+
+			repo, err = git.PlainOpen(subPath)
+
+			return err
+		})
+		if err != nil {
+			log.Error("open repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return &OpenError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+		}
+
+		if c.refreshRemotes {
+			if err := refreshRemote(log, repo, repoURL); err != nil {
+				log.Error("refresh remote error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("refresh remote %s: %w", project.PathWithNamespace, err)
+			}
+		}
+	}
+
+	var before plumbing.Hash
+
+	if head, err := repo.Head(); err == nil && !cloned {
+		before = head.Hash()
+	}
+
+	// With clone only new just fresh clones get here, they are up to date.
+	switch {
+	case c.cloneOnlyNew:
+	case c.bare:
+		// Bare repos have no worktree to pull into, so the remote refs
+		// are force fetched straight into the local ones.
+		err = c.retry(ctx, log, func() error {
+			return repo.FetchContext(ctx, &git.FetchOptions{
+				RemoteName:      "origin",
+				RefSpecs:        c.bareRefSpecs(),
+				Auth:            c.repoAuth(repo),
+				Progress:        c.progress,
+				Depth:           depth,
+				Force:           true,
+				ProxyOptions:    c.repoProxyOptions(repo),
+				CABundle:        c.caBundle,
+				InsecureSkipTLS: c.insecureSkipTLS,
+			})
+		})
+		if c.branch != "" && isBranchNotFound(err) {
+			log.Warn("branch not found", slog.String("branch", c.branch))
+			c.record(result, StatusSkipped)
+
+			return nil
+		}
+
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return c.gitEmpty(log, result, project, root, subPath, repoURL)
+		}
+
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			log.Error("fetch repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return &PullError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+		}
+	default:
+		work, err := repo.Worktree()
+		if err != nil {
+			log.Error("worktree repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("worktree repo %s: %w", project.PathWithNamespace, err)
+		}
+
+		if cloned && len(c.sparsePaths) > 0 {
+			if err := c.sparseCheckout(ctx, subPath); err != nil {
+				log.Error("sparse checkout error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("sparse checkout %s: %w", project.PathWithNamespace, err)
+			}
+		}
+
+		// In fetch only mode existing worktrees are never touched, only the
+		// remote refs are updated.
+		fetchOnly := c.fetchOnly && !cloned
+
+		restore := func() error { return nil }
+
+		if c.stashBeforePull && !cloned && !fetchOnly {
+			stashed, err := c.stash(ctx, log, work, subPath)
+			if err != nil {
+				log.Error("stash error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("stash %s: %w", project.PathWithNamespace, err)
+			}
+
+			if stashed {
+				restore = sync.OnceValue(func() error {
+					return c.unstash(ctx, log, subPath)
+				})
+
+				// Early returns before the pull still restore the changes.
+				defer restore()
+			}
+		}
+
+		if c.noForcePull && !cloned && !fetchOnly {
+			isDirty, err := dirty(work)
+			if err != nil {
+				log.Error("worktree status error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("worktree status %s: %w", project.PathWithNamespace, err)
+			}
+
+			if isDirty {
+				log.Warn("local changes, pull skipped")
+				c.record(result, StatusConflict)
+
+				return nil
+			}
+		}
+
+		if !cloned && !fetchOnly && c.branch == "" && c.ref == "" && len(c.sparsePaths) == 0 && project.DefaultBranch != "" {
+			err = c.retry(ctx, log, func() error {
+				return c.checkoutDefaultBranch(ctx, log, repo, work, project, depth)
+			})
+			if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+				return c.gitEmpty(log, result, project, root, subPath, repoURL)
+			}
+
+			if err != nil {
+				log.Error("default branch error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("default branch %s: %w", project.PathWithNamespace, err)
+			}
+
+			pullOptions.ReferenceName = plumbing.NewBranchReferenceName(project.DefaultBranch)
+		}
+
+		// A depth on an existing full clone only limits the newly fetched
+		// commits, the history already on disk is kept as is.
+		err = c.retry(ctx, log, func() error {
+			if c.ref != "" || fetchOnly {
+				return c.fetchRef(ctx, repo, depth)
+			}
+
+			if len(c.sparsePaths) > 0 {
+				return c.pullSparse(ctx, repo, subPath)
+			}
+
+			if c.pullStrategy == PullStrategyRebase {
+				return c.pullRebase(ctx, repo, subPath)
+			}
+
+			pullOptions.Auth = c.repoAuth(repo)
+			pullOptions.ProxyOptions = c.repoProxyOptions(repo)
+
+			return work.PullContext(ctx, pullOptions)
+		})
+
+		restoreErr := restore()
+
+		if c.branch != "" && isBranchNotFound(err) {
+			log.Warn("branch not found", slog.String("branch", c.branch))
+			c.record(result, StatusSkipped)
+
+			return nil
+		}
+
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return c.gitEmpty(log, result, project, root, subPath, repoURL)
+		}
+
+		if err != nil && isNoAccess(err) {
+			log.Warn("no access to repo", slog.String("error", err.Error()))
+			c.record(result, StatusNoAccess)
+
+			return nil
+		}
+
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			log.Error("pull repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return &PullError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+		}
+
+		if restoreErr != nil {
+			log.Warn("local changes left in the stash")
+			c.record(result, StatusConflict)
+
+			return nil
+		}
+
+		if c.ref != "" && !fetchOnly {
+			if err := c.checkoutRef(log, repo, work); err != nil {
+				log.Error("checkout ref error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("checkout ref %s: %w", project.PathWithNamespace, err)
+			}
+		}
+
+		if c.branchPattern != "" && !fetchOnly {
+			if err := c.trackBranches(log, repo); err != nil {
+				log.Error("track branches error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("track branches %s: %w", project.PathWithNamespace, err)
+			}
+		}
+
+		if c.recurseSubmodules && !fetchOnly {
+			err = c.retry(ctx, log, func() error {
+				return c.updateSubmodules(ctx, work, depth)
+			})
+			if err != nil {
+				log.Error("submodules error", slog.String("error", err.Error()))
+				c.record(result, StatusFailed)
+
+				return fmt.Errorf("submodules %s: %w", project.PathWithNamespace, err)
+			}
+		}
+	}
+
+	return c.finishClone(ctx, log, result, project, repo, root, subPath, depth, cloned, before)
+}
+
+// finishClone runs the steps shared by every clone mode on the local repo
+// and records the result.
+func (c *Cloner) finishClone(ctx context.Context, log *slog.Logger, result *Result, project *gitlab.Project, repo *git.Repository, root, subPath string, depth int, cloned bool, before plumbing.Hash) error {
+	if len(c.refSpecs) > 0 {
+		err := c.retry(ctx, log, func() error {
+			return c.fetchRefSpecs(ctx, repo, depth)
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			log.Error("fetch refspecs error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return &PullError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+		}
+	}
+
+	if c.lfs {
+		if err := c.lfsPull(ctx, log, subPath); err != nil {
+			log.Error("lfs pull error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("lfs pull %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	// A failed gc leaves the repo as it was, so it is only reported.
+	if c.gcRepo {
+		if err := c.gc(ctx, log, subPath); err != nil {
+			log.Warn("gc repo error", slog.String("error", err.Error()))
+		}
+	}
+
+	if c.verify {
+		if err := c.fsck(ctx, log, subPath); err != nil {
+			log.Error("verify repo error", slog.String("error", err.Error()))
+
+			if errors.Is(err, errCorrupt) {
+				c.record(result, StatusCorrupt)
+			} else {
+				c.record(result, StatusFailed)
+			}
+
+			return fmt.Errorf("verify repo %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	if c.mirrorTo != "" {
+		if err := c.mirror(ctx, log, repo, project); err != nil {
+			log.Error("mirror repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("mirror repo %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	if c.stateFile != "" {
+		if err := c.markCompleted(project.ID); err != nil {
+			log.Warn("state file error", slog.String("error", err.Error()))
+		}
+	}
+
+	if head, err := repo.Head(); err == nil {
+		result.SHA = head.Hash().String()
+
+		if !before.IsZero() {
+			if commits, ok := commitsBetween(repo, before, head.Hash()); ok {
+				log.Info("fetched commits", slog.Int("commits", commits))
+
+				result.Commits = commits
+			}
+		}
+	}
+
+	if err := c.applyPerms(root, subPath); err != nil {
+		log.Warn("permissions error", slog.String("error", err.Error()))
+	}
+
+	status := StatusPulled
+	if cloned {
+		status = StatusCloned
+	}
+
+	if c.postCloneHookCmd != "" {
+		c.postCloneHook(ctx, log, project, subPath, status)
+	}
+
+	c.record(result, status)
+
+	return nil
+}
+
+// gitEmpty records a project without commits as empty after setting up
+// its local repo.
+func (c *Cloner) gitEmpty(log *slog.Logger, result *Result, project *gitlab.Project, root, subPath, repoURL string) error {
+	if err := c.initEmpty(subPath, repoURL); err != nil {
+		log.Error("init empty repo error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return fmt.Errorf("init empty repo %s: %w", project.PathWithNamespace, err)
+	}
+
+	if err := c.applyPerms(root, subPath); err != nil {
+		log.Warn("permissions error", slog.String("error", err.Error()))
+	}
+
+	log.Info("empty repo")
+	c.record(result, StatusEmpty)
+
+	return nil
+}
+
+func (c *Cloner) bareRefSpecs() []config.RefSpec {
+	if c.branch != "" {
+		ref := plumbing.NewBranchReferenceName(c.branch)
+
+		return []config.RefSpec{
+			config.RefSpec("+" + ref + ":" + ref),
+		}
+	}
+
+	return []config.RefSpec{
+		"+refs/heads/*:refs/heads/*",
+		"+refs/tags/*:refs/tags/*",
+	}
+}
+
+func isBranchNotFound(err error) bool {
+	return errors.Is(err, git.NoMatchingRefSpecError{}) || errors.Is(err, plumbing.ErrReferenceNotFound)
+}
+
+func (c *Cloner) repoURL(project *gitlab.Project) string {
+	return urlFor(c.authMethod, project)
+}
@@ -0,0 +1,137 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestCloneTimeout(t *testing.T) {
+	// The remote never answers, only the clone timeout ends the clone.
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		AuthMethod:   AuthMethodHTTPToken,
+		CloneTimeout: 100 * time.Millisecond,
+	})
+
+	started := time.Now()
+
+	err := c.gitClone(context.Background(), testProject(1, "acme/repo", server.URL+"/acme/repo.git"), c.destDir, "acme")
+
+	var cloneErr *CloneError
+	if !errors.As(err, &cloneErr) {
+		t.Errorf("gitClone error = %v, want a clone error", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("clone took %s, want the timeout to stop it", elapsed)
+	}
+
+	if got := c.Stats().Failed; got != 1 {
+		t.Errorf("failed = %d, want 1", got)
+	}
+}
+
+func TestBareClone(t *testing.T) {
+	src := newSourceRepo(t)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{Bare: true})
+	project := testProject(1, "acme/repo", src)
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(c.destDir, "acme", "repo")
+
+	if _, err := os.Stat(filepath.Join(dir, "README")); !os.IsNotExist(err) {
+		t.Errorf("README checked out: %v", err)
+	}
+
+	source, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := commitFile(t, source, src, "NEWS")
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Worktree(); !errors.Is(err, git.ErrIsBareRepository) {
+		t.Errorf("Worktree error = %v, want %v", err, git.ErrIsBareRepository)
+	}
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ref.Hash() != hash {
+		t.Errorf("master = %s, want %s", ref.Hash(), hash)
+	}
+
+	stats := c.Stats()
+	if stats.Cloned != 1 || stats.Pulled != 1 {
+		t.Errorf("stats = %+v, want 1 cloned and 1 pulled", stats)
+	}
+}
+
+func TestNoForcePullConflict(t *testing.T) {
+	src := newSourceRepo(t)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{NoForcePull: true})
+	project := testProject(1, "acme/repo", src)
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(c.destDir, "acme", "repo")
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, source, src, "NEWS")
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "NEWS")); !os.IsNotExist(err) {
+		t.Errorf("dirty worktree pulled: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "README"))
+	if err != nil || string(data) != "local\n" {
+		t.Errorf("README = %q, %v, want the local change", data, err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 1 || stats.Conflict != 1 {
+		t.Errorf("stats = %+v, want 1 cloned and 1 conflict", stats)
+	}
+}
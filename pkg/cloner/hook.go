@@ -0,0 +1,33 @@
+package cloner
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// postCloneHook runs the hook command with sh inside the repo. Failures
+// are counted apart from the repo status, the repo itself is up to date.
+func (c *Cloner) postCloneHook(ctx context.Context, log *slog.Logger, project *gitlab.Project, subPath, status string) {
+	env := append(os.Environ(),
+		"REPO_PATH="+subPath,
+		"PROJECT_ID="+strconv.Itoa(project.ID),
+		"PROJECT_PATH="+project.PathWithNamespace,
+		"SSH_URL="+project.SSHURLToRepo,
+		"REPO_STATUS="+status,
+	)
+
+	out, err := runShell(ctx, subPath, env, c.postCloneHookCmd)
+	if len(out) > 0 {
+		log.Info("hook output", slog.String("output", strings.TrimSpace(string(out))))
+	}
+
+	if err != nil {
+		log.Error("hook error", slog.String("error", err.Error()))
+		c.inc(&c.stats.HookFailed)
+	}
+}
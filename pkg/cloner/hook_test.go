@@ -0,0 +1,63 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPostCloneHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.env")
+
+	hook := `printf '%s\n' "$REPO_PATH" "$PROJECT_ID" "$PROJECT_PATH" "$SSH_URL" "$REPO_STATUS" >> ` + out
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{PostCloneHook: hook})
+
+	src := newSourceRepo(t)
+	project := testProject(7, "acme/repo", src)
+
+	for range 2 {
+		if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoPath := filepath.Join(c.destDir, "acme", "repo")
+
+	want := []string{
+		repoPath, "7", "acme/repo", src, StatusCloned,
+		repoPath, "7", "acme/repo", src, StatusPulled,
+	}
+
+	if got := strings.Split(strings.TrimSpace(string(data)), "\n"); !slices.Equal(got, want) {
+		t.Errorf("hook env = %q, want %q", got, want)
+	}
+
+	if got := c.Stats().HookFailed; got != 0 {
+		t.Errorf("hook failed = %d, want 0", got)
+	}
+}
+
+func TestPostCloneHookFailed(t *testing.T) {
+	c := newTestCloner(t, "http://127.0.0.1", Options{PostCloneHook: "exit 3"})
+
+	project := testProject(7, "acme/repo", newSourceRepo(t))
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Stats()
+
+	if stats.HookFailed != 1 || stats.Cloned != 1 {
+		t.Errorf("hook failed = %d, cloned = %d, want 1 and 1", stats.HookFailed, stats.Cloned)
+	}
+}
@@ -0,0 +1,38 @@
+package cloner
+
+import (
+	"context"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// acquireHost blocks until a clone slot for the host of repoURL is free
+// and returns the func releasing it. Without a per host limit it returns
+// straight away.
+func (c *Cloner) acquireHost(ctx context.Context, repoURL string) (func(), error) {
+	if c.maxClonesPerHost == 0 {
+		return func() {}, nil
+	}
+
+	host := repoURL
+	if endpoint, err := transport.NewEndpoint(repoURL); err == nil {
+		host = endpoint.Host
+	}
+
+	c.hostSlotsMu.Lock()
+
+	slots, ok := c.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, c.maxClonesPerHost)
+		c.hostSlots[host] = slots
+	}
+
+	c.hostSlotsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
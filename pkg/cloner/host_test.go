@@ -0,0 +1,33 @@
+package cloner
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMaxClonesPerHost(t *testing.T) {
+	inspect, highest := concurrencyProbe()
+
+	// Local paths all have the same empty host, so they share one slot.
+	api := sourceGroup(6, newSourceRepo(t))
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:         []int{1},
+		Concurrency:      4,
+		MaxClonesPerHost: 1,
+		InMemory:         true,
+		Inspect:          inspect,
+	})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Stats().Cloned; got != 6 {
+		t.Errorf("cloned = %d, want 6", got)
+	}
+
+	if got := highest.Load(); got != 1 {
+		t.Errorf("concurrent clones = %d, want 1", got)
+	}
+}
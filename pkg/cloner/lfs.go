@@ -0,0 +1,32 @@
+package cloner
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// lfsPull fetches Git LFS objects with the git-lfs binary, since go-git
+// leaves pointer files only. Missing binaries are reported and skipped.
+func (c *Cloner) lfsPull(ctx context.Context, log *slog.Logger, subPath string) error {
+	for _, bin := range []string{"git", "git-lfs"} {
+		if _, err := lookPath(bin); err != nil {
+			log.Warn("lfs skipped", slog.String("error", err.Error()))
+
+			return nil
+		}
+	}
+
+	// Bare and tags only repos have nothing checked out to pull into.
+	args := []string{"lfs", "pull"}
+	if c.bare || c.tagsOnly {
+		args = []string{"lfs", "fetch", "--all"}
+	}
+
+	out, err := runCommand(ctx, subPath, nil, "git", args...)
+	if len(out) > 0 {
+		log.Debug("lfs output", slog.String("output", strings.TrimSpace(string(out))))
+	}
+
+	return err
+}
@@ -0,0 +1,53 @@
+package cloner
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestLFSPull(t *testing.T) {
+	tests := []struct {
+		name    string
+		lfs     bool
+		bare    bool
+		missing []string
+		want    string
+	}{
+		{name: "disabled"},
+		{name: "enabled", lfs: true, want: "git lfs pull"},
+		{name: "bare", lfs: true, bare: true, want: "git lfs fetch --all"},
+		{name: "missing git-lfs", lfs: true, missing: []string{"git-lfs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeCommands(t, tt.missing...)
+
+			c := newTestCloner(t, "http://127.0.0.1", Options{LFS: tt.lfs, Bare: tt.bare})
+
+			project := testProject(1, "acme/repo", newSourceRepo(t))
+
+			if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+				t.Fatal(err)
+			}
+
+			lfsCalls := slices.DeleteFunc(slices.Clone(*calls), func(call string) bool {
+				return call != "git lfs pull" && call != "git lfs fetch --all"
+			})
+
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+
+			if !slices.Equal(lfsCalls, want) {
+				t.Errorf("lfs calls = %q, want %q", lfsCalls, want)
+			}
+
+			if got := c.Stats().Cloned; got != 1 {
+				t.Errorf("cloned = %d, want 1", got)
+			}
+		})
+	}
+}
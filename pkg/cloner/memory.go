@@ -0,0 +1,105 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/xanzy/go-gitlab"
+)
+
+// InspectFunc is called with every repo cloned in memory.
+type InspectFunc func(ctx context.Context, project *gitlab.Project, repo *git.Repository) error
+
+// inMemoryConflict returns the first option that needs repos on disk.
+func (c *Cloner) inMemoryConflict() string {
+	options := []struct {
+		name string
+		set  bool
+	}{
+		{"prune", c.prune},
+		{"bare", c.bare},
+		{"lfs", c.lfs},
+		{"skip pull", c.skipPull},
+		{"mirror", c.mirrorTo != ""},
+		{"ref", c.ref != ""},
+		{"submodules", c.recurseSubmodules},
+		{"state file", c.stateFile != ""},
+		{"verify", c.verify},
+		{"gc", c.gcRepo},
+		{"post clone hook", c.postCloneHookCmd != ""},
+		{"only empty dir", c.onlyEmptyDir},
+		{"fetch only", c.fetchOnly},
+		{"clone only new", c.cloneOnlyNew},
+		{"refspecs", len(c.refSpecs) > 0},
+		{"stash before pull", c.stashBeforePull},
+		{"branch pattern", c.branchPattern != ""},
+		{"sparse paths", len(c.sparsePaths) > 0},
+	}
+
+	for _, option := range options {
+		if option.set {
+			return option.name
+		}
+	}
+
+	return ""
+}
+
+// memoryClone clones the project into memory and passes it to inspect,
+// nothing is written to disk.
+func (c *Cloner) memoryClone(ctx context.Context, log *slog.Logger, result *Result, project *gitlab.Project, cloneOptions *git.CloneOptions) error {
+	if project.EmptyRepo {
+		log.Info("empty repo")
+		c.record(result, StatusEmpty)
+
+		return nil
+	}
+
+	var repo *git.Repository
+
+	err := c.retry(ctx, log, func() error {
+		var err error
+
+		repo, err = git.CloneContext(ctx, memory.NewStorage(), memfs.New(), cloneOptions)
+
+		return err
+	})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		log.Info("empty repo")
+		c.record(result, StatusEmpty)
+
+		return nil
+	}
+
+	if err != nil {
+		log.Error("clone repo error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return &CloneError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+	}
+
+	head, err := repo.Head()
+	if err == nil {
+		result.SHA = head.Hash().String()
+	}
+
+	if c.inspect != nil {
+		if err := c.inspect(ctx, project, repo); err != nil {
+			log.Error("inspect repo error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("inspect %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	log.Info("cloned in memory", slog.String("sha", result.SHA))
+	c.record(result, StatusCloned)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cloner
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestMemoryCloneInspect(t *testing.T) {
+	var readme string
+
+	inspect := func(_ context.Context, _ *gitlab.Project, repo *git.Repository) error {
+		work, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+
+		file, err := work.Filesystem.Open("README")
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		readme = string(data)
+
+		return err
+	}
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{InMemory: true, Inspect: inspect})
+
+	if err := c.gitClone(context.Background(), testProject(1, "acme/repo", newSourceRepo(t)), c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if readme != "readme\n" {
+		t.Errorf("README = %q, want %q", readme, "readme\n")
+	}
+
+	entries, err := os.ReadDir(c.destDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("dest dir entries = %v, %v, want none", entries, err)
+	}
+
+	if got := c.Stats().Cloned; got != 1 {
+		t.Errorf("cloned = %d, want 1", got)
+	}
+}
@@ -0,0 +1,42 @@
+package cloner
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the Prometheus collectors updated while repos are cloned.
+// One Metrics can be shared by several cloners.
+type Metrics struct {
+	repos    *prometheus.CounterVec
+	duration prometheus.Histogram
+}
+
+// NewMetrics creates the collectors and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		repos: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gitlab_repo_cloner_repos_total",
+			Help: "Repos processed by status.",
+		}, []string{"status"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "gitlab_repo_cloner_repo_duration_seconds",
+			Help:    "Time spent cloning or pulling a single repo.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		}),
+	}
+
+	reg.MustRegister(m.repos, m.duration)
+
+	return m
+}
+
+func (m *Metrics) observe(status string, start time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.repos.WithLabelValues(status).Inc()
+	m.duration.Observe(time.Since(start).Seconds())
+}
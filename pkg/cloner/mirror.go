@@ -0,0 +1,94 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+)
+
+const mirrorRemote = "mirror"
+
+func (c *Cloner) mirrorURL(project *gitlab.Project) string {
+	return strings.TrimSuffix(c.mirrorTo, "/") + "/" + project.PathWithNamespace + ".git"
+}
+
+// mirror points the mirror remote at the derived URL and force pushes
+// all branches and tags to it, pruning refs that are gone locally. The
+// push uses the mirror auth only, without it ssh falls back to the agent.
+func (c *Cloner) mirror(ctx context.Context, log *slog.Logger, repo *git.Repository, project *gitlab.Project) error {
+	mirrorURL := c.mirrorURL(project)
+
+	log = log.With(slog.String("mirror", mirrorURL))
+
+	remote, err := repo.Remote(mirrorRemote)
+	if err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+
+	if remote != nil && !slices.Equal(remote.Config().URLs, []string{mirrorURL}) {
+		if err := repo.DeleteRemote(mirrorRemote); err != nil {
+			return err
+		}
+
+		remote = nil
+	}
+
+	if remote == nil {
+		_, err := repo.CreateRemote(&config.RemoteConfig{
+			Name: mirrorRemote,
+			URLs: []string{mirrorURL},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Non bare clones keep only the default branch under refs/heads, the
+	// rest of the branches live under the origin remote refs.
+	heads := config.RefSpec("+refs/remotes/origin/*:refs/heads/*")
+	if c.bare {
+		heads = "+refs/heads/*:refs/heads/*"
+	}
+
+	refSpecs := []config.RefSpec{heads, tagsRefSpec}
+
+	// Tags only repos have no branches, pushing the empty branches with
+	// prune would delete the ones of the mirror.
+	if c.tagsOnly {
+		refSpecs = []config.RefSpec{tagsRefSpec}
+	}
+
+	err = c.retry(ctx, log, func() error {
+		return repo.PushContext(ctx, &git.PushOptions{
+			RemoteName:      mirrorRemote,
+			RefSpecs:        refSpecs,
+			Auth:            c.mirrorAuth,
+			Progress:        c.progress,
+			Force:           true,
+			Prune:           true,
+			ProxyOptions:    c.proxyOptions(mirrorURL),
+			CABundle:        c.caBundle,
+			InsecureSkipTLS: c.insecureSkipTLS,
+		})
+	})
+	if errors.Is(err, transport.ErrRepositoryNotFound) {
+		log.Warn("mirror repository not found, create it on the target host first")
+
+		return nil
+	}
+
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	log.Info("mirror repo")
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package cloner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Owner is the user and group repos are handed over to, -1 keeps the
+// current one.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// applyPerms sets the directory mode and owner on the repo at subPath and
+// on its parent directories up to root.
+func (c *Cloner) applyPerms(root, subPath string) error {
+	if c.dirMode == 0 && c.owner == nil {
+		return nil
+	}
+
+	err := filepath.WalkDir(subPath, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return c.setPerm(name, entry.IsDir())
+	})
+	if err != nil {
+		return err
+	}
+
+	root = filepath.Clean(root)
+
+	for dir := filepath.Dir(subPath); dir != root; dir = filepath.Dir(dir) {
+		if rel, err := filepath.Rel(root, dir); err != nil || !filepath.IsLocal(rel) {
+			break
+		}
+
+		if err := c.setPerm(dir, true); err != nil {
+			return err
+		}
+	}
+
+	return c.setPerm(root, true)
+}
+
+func (c *Cloner) setPerm(name string, isDir bool) error {
+	if isDir && c.dirMode != 0 {
+		if err := os.Chmod(name, c.dirMode); err != nil {
+			return err
+		}
+	}
+
+	if c.owner != nil {
+		return os.Lchown(name, c.owner.UID, c.owner.GID)
+	}
+
+	return nil
+}
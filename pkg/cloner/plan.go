@@ -0,0 +1,20 @@
+package cloner
+
+import (
+	"github.com/go-git/go-git/v5"
+)
+
+const (
+	ActionClone = "clone"
+	ActionPull  = "pull"
+)
+
+// plannedAction reports whether a dry run would clone subPath afresh or
+// pull the repo already there.
+func plannedAction(subPath string) string {
+	if _, err := git.PlainOpen(subPath); err == nil {
+		return ActionPull
+	}
+
+	return ActionClone
+}
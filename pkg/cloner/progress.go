@@ -0,0 +1,48 @@
+package cloner
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// progressBar renders a single updating line with the number of repos
+// processed so far.
+type progressBar struct {
+	mu    sync.Mutex
+	w     io.Writer
+	total int
+	done  int
+}
+
+func (p *progressBar) start(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total = total
+	p.done = 0
+
+	p.render()
+}
+
+func (p *progressBar) inc() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+
+	p.render()
+}
+
+func (p *progressBar) render() {
+	percent := 100
+	if p.total > 0 {
+		percent = p.done * 100 / p.total
+	}
+
+	fmt.Fprintf(p.w, "\r%d/%d repos (%d%%)", p.done, p.total, percent)
+
+	if p.done >= p.total {
+		fmt.Fprintln(p.w)
+	}
+}
@@ -0,0 +1,49 @@
+package cloner
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// validateProxy checks the proxy scheme is one git or the API client can
+// connect through.
+func validateProxy(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	default:
+		return fmt.Errorf("unknown proxy scheme %q", u.Scheme)
+	}
+}
+
+// proxyOptions returns the proxy for git network operations with the
+// remote. ssh only connects through socks5 proxies, an http proxy is kept
+// for http remotes and the API. Without an explicit proxy go-git falls
+// back to the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+func (c *Cloner) proxyOptions(remoteURL string) transport.ProxyOptions {
+	if !isHTTPURL(remoteURL) && !strings.HasPrefix(c.proxy, "socks5") {
+		return transport.ProxyOptions{}
+	}
+
+	return transport.ProxyOptions{
+		URL: c.proxy,
+	}
+}
+
+// repoProxyOptions returns the proxy for the origin remote of the repo.
+func (c *Cloner) repoProxyOptions(repo *git.Repository) transport.ProxyOptions {
+	return c.proxyOptions(originURL(repo))
+}
+
+func isHTTPURL(remoteURL string) bool {
+	return strings.HasPrefix(remoteURL, "http://") || strings.HasPrefix(remoteURL, "https://")
+}
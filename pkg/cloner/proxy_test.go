@@ -0,0 +1,43 @@
+package cloner
+
+import "testing"
+
+func TestProxyOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     string
+		remoteURL string
+		want      string
+	}{
+		{name: "http proxy https remote", proxy: "http://proxy:3128", remoteURL: "https://gitlab.com/acme/repo.git", want: "http://proxy:3128"},
+		{name: "http proxy ssh remote", proxy: "http://proxy:3128", remoteURL: "git@gitlab.com:acme/repo.git", want: ""},
+		{name: "http proxy ssh url remote", proxy: "http://proxy:3128", remoteURL: "ssh://git@gitlab.com/acme/repo.git", want: ""},
+		{name: "socks proxy ssh remote", proxy: "socks5://proxy:1080", remoteURL: "git@gitlab.com:acme/repo.git", want: "socks5://proxy:1080"},
+		{name: "socks proxy https remote", proxy: "socks5h://proxy:1080", remoteURL: "https://gitlab.com/acme/repo.git", want: "socks5h://proxy:1080"},
+		{name: "no proxy", remoteURL: "https://gitlab.com/acme/repo.git", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cloner{proxy: tt.proxy}
+
+			if got := c.proxyOptions(tt.remoteURL).URL; got != tt.want {
+				t.Errorf("proxy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	for proxy, wantErr := range map[string]bool{
+		"http://proxy:3128":   false,
+		"https://proxy:3128":  false,
+		"socks5://proxy:1080": false,
+		"ftp://proxy:21":      true,
+		"proxy:3128":          true,
+	} {
+		if err := validateProxy(proxy); (err != nil) != wantErr {
+			t.Errorf("validateProxy(%q) = %v, want error %t", proxy, err, wantErr)
+		}
+	}
+}
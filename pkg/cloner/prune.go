@@ -0,0 +1,133 @@
+package cloner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/xanzy/go-gitlab"
+)
+
+const trashDir = ".trash"
+
+func (c *Cloner) markSeen(subPath string) {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+
+	if c.seen == nil {
+		c.seen = map[string]bool{}
+	}
+
+	c.seen[filepath.Clean(subPath)] = true
+}
+
+// keep marks the paths of a project that is filtered out as seen, so a
+// clone of it from an earlier run is not pruned.
+func (c *Cloner) keep(project *gitlab.Project, root, dest string) {
+	if !c.prune {
+		return
+	}
+
+	projects := []*gitlab.Project{project}
+	if c.includeWikis && wikiEnabled(project) {
+		projects = append(projects, wikiProject(project))
+	}
+
+	for _, project := range projects {
+		subPath, err := c.repoPath(project, dest)
+		if err != nil {
+			continue
+		}
+
+		c.markSeen(path.Join(root, subPath))
+	}
+}
+
+// pruneConflict returns an error when an option stops enumeration before
+// every existing project is listed, prune would trash the projects that
+// were never seen.
+func (c *Cloner) pruneConflict(opts Options) error {
+	switch {
+	case c.limit > 0:
+		return errors.New("prune can not be used with a limit")
+	case opts.MaxGroupDepth != nil:
+		return errors.New("prune can not be used with a max group depth")
+	case len(c.ignoreGroupIDs) > 0 || len(c.ignoreGroupPaths) > 0:
+		return errors.New("prune can not be used with ignored groups")
+	case len(c.ignoreProjectIDs) > 0:
+		return errors.New("prune can not be used with ignored projects")
+	}
+
+	return nil
+}
+
+// Prune moves git repositories under destDir that were not seen during
+// this run into a timestamped trash folder instead of deleting them.
+func (c *Cloner) Prune() error {
+	destDir := filepath.Clean(c.destDir)
+	trash := filepath.Join(destDir, trashDir, time.Now().Format("20060102150405"))
+
+	err := filepath.WalkDir(destDir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() || name == destDir {
+			return nil
+		}
+
+		if entry.Name() == trashDir && filepath.Dir(name) == destDir {
+			return filepath.SkipDir
+		}
+
+		if _, err := git.PlainOpen(name); err != nil {
+			return nil
+		}
+
+		if c.seen[name] {
+			return filepath.SkipDir
+		}
+
+		log := slog.With(slog.String("path", name))
+
+		if c.dryRun {
+			log.Info("dry run prune")
+
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(destDir, name)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(trash, rel)
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		if err := os.Rename(name, target); err != nil {
+			return err
+		}
+
+		log.Info("prune repo", slog.String("trash", target))
+		c.inc(&c.stats.Pruned)
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		slog.Error("prune error", slog.String("error", err.Error()))
+		c.inc(&c.stats.Failed)
+
+		return fmt.Errorf("prune: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestPruneKeepsFilteredProjects(t *testing.T) {
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{testGroup(1, 0, "acme")},
+		projects: map[int][]*gitlab.Project{
+			1: {testProject(11, "acme/a", newSourceRepo(t))},
+		},
+	}
+
+	apiURL := api.start(t)
+	destDir := t.TempDir()
+
+	first := newTestCloner(t, apiURL, Options{DestDir: destDir, GroupIDs: []int{1}})
+	if err := first.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := filepath.Join(destDir, "old", "gone")
+	if _, err := git.PlainInit(stale, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project is not public, so the visibility filter skips it this run.
+	second := newTestCloner(t, apiURL, Options{
+		DestDir:    destDir,
+		GroupIDs:   []int{1},
+		Visibility: []string{"public"},
+		Prune:      true,
+	})
+	if err := second.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "acme", "a")); err != nil {
+		t.Errorf("filtered project was pruned: %v", err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale repo was not pruned: %v", err)
+	}
+
+	if got := second.Stats().Pruned; got != 1 {
+		t.Errorf("pruned = %d, want 1", got)
+	}
+}
+
+func TestPruneConflict(t *testing.T) {
+	depth := 1
+
+	for name, opts := range map[string]Options{
+		"limit":           {Limit: 1},
+		"max group depth": {MaxGroupDepth: &depth},
+		"ignored group":   {IgnoreGroupIDs: []int{1}},
+		"ignored path":    {IgnoreGroupPaths: []string{"acme"}},
+		"ignored project": {IgnoreProjectIDs: []int{1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := gitlab.NewClient("token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts.Client = client
+			opts.Prune = true
+
+			if _, err := New(opts); err == nil {
+				t.Error("New succeeded, want a prune conflict")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cloner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+const (
+	PullStrategyMerge  = "merge"
+	PullStrategyRebase = "rebase"
+	PullStrategyFFOnly = "ff-only"
+)
+
+// pullOptions returns the go-git pull options of the pull strategy, only
+// merge forces the update of a diverged local branch.
+func (c *Cloner) pullOptions(depth int) *git.PullOptions {
+	pullOptions := &git.PullOptions{
+		RemoteName:      "origin",
+		Force:           c.pullStrategy == PullStrategyMerge && !c.noForcePull,
+		Progress:        c.progress,
+		Depth:           depth,
+		CABundle:        c.caBundle,
+		InsecureSkipTLS: c.insecureSkipTLS,
+	}
+
+	if c.branch != "" {
+		pullOptions.ReferenceName = plumbing.NewBranchReferenceName(c.branch)
+		pullOptions.SingleBranch = true
+	}
+
+	return pullOptions
+}
+
+// pullRebase shells out to the git binary because go-git can only
+// fast-forward or force update on pull.
+func (c *Cloner) pullRebase(ctx context.Context, repo *git.Repository, subPath string) error {
+	return c.pullBinary(ctx, repo, subPath, "--rebase")
+}
+
+// pullSparse pulls a sparse worktree with the git binary, a go-git pull
+// would bring back the files outside the sparse paths.
+func (c *Cloner) pullSparse(ctx context.Context, repo *git.Repository, subPath string) error {
+	if c.pullStrategy == PullStrategyRebase {
+		return c.pullRebase(ctx, repo, subPath)
+	}
+
+	return c.pullBinary(ctx, repo, subPath, "--ff-only")
+}
+
+func (c *Cloner) pullBinary(ctx context.Context, repo *git.Repository, subPath string, mode string) error {
+	args := []string{"pull", mode, "origin"}
+	if c.branch != "" {
+		args = append(args, c.branch)
+	}
+
+	out, err := runCommand(ctx, subPath, c.binaryEnv(repo), "git", args...)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// binaryEnv returns the environment of the git binary with the token,
+// proxy and TLS verification go-git would use for the repo. They are set
+// as git config through the environment so the token does not show up in
+// the process list.
+func (c *Cloner) binaryEnv(repo *git.Repository) []string {
+	var config [][2]string
+
+	if auth, ok := c.repoAuth(repo).(*githttp.BasicAuth); ok {
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		config = append(config, [2]string{"http.extraHeader", "Authorization: Basic " + credentials})
+	}
+
+	if c.proxy != "" {
+		config = append(config, [2]string{"http.proxy", c.proxy})
+	}
+
+	if c.insecureSkipTLS {
+		config = append(config, [2]string{"http.sslVerify", "false"})
+	}
+
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT="+strconv.Itoa(len(config)))
+
+	for i, kv := range config {
+		env = append(env,
+			"GIT_CONFIG_KEY_"+strconv.Itoa(i)+"="+kv[0],
+			"GIT_CONFIG_VALUE_"+strconv.Itoa(i)+"="+kv[1],
+		)
+	}
+
+	return env
+}
+
+// binaryPullConflict returns the name of an option the git binary can not
+// honour, it pulls over ssh with the user's agent and known_hosts only.
+func (c *Cloner) binaryPullConflict() string {
+	if len(c.caBundle) > 0 {
+		return "a CA bundle"
+	}
+
+	for _, option := range c.auths() {
+		switch auth := option.Auth.(type) {
+		case *gitssh.PublicKeys:
+			return "ssh key auth"
+		case *gitssh.PublicKeysCallback:
+			if auth.User != gitssh.DefaultUsername || auth.HostKeyCallback != nil {
+				return "a custom ssh user or host key mode"
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,46 @@
+package cloner
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// fetchRef updates remote refs and tags without touching the worktree,
+// a pull is not possible once HEAD is detached at the requested ref.
+func (c *Cloner) fetchRef(ctx context.Context, repo *git.Repository, depth int) error {
+	return repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      "origin",
+		Auth:            c.repoAuth(repo),
+		Progress:        c.progress,
+		Depth:           depth,
+		Tags:            git.AllTags,
+		Force:           true,
+		ProxyOptions:    c.repoProxyOptions(repo),
+		CABundle:        c.caBundle,
+		InsecureSkipTLS: c.insecureSkipTLS,
+	})
+}
+
+// checkoutRef detaches the worktree at the configured tag or commit. A
+// ref missing from the repo is reported and the worktree is left as is.
+func (c *Cloner) checkoutRef(log *slog.Logger, repo *git.Repository, work *git.Worktree) error {
+	log = log.With(slog.String("ref", c.ref))
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(c.ref))
+	if err != nil {
+		log.Warn("ref not found", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if err := work.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		return err
+	}
+
+	log.Info("checkout ref", slog.String("hash", hash.String()))
+
+	return nil
+}
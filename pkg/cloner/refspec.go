@@ -0,0 +1,22 @@
+package cloner
+
+import (
+	"context"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// fetchRefSpecs fetches the custom refspecs, e.g. merge request refs,
+// which clone and pull do not take.
+func (c *Cloner) fetchRefSpecs(ctx context.Context, repo *git.Repository, depth int) error {
+	return repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName:      "origin",
+		RefSpecs:        c.refSpecs,
+		Auth:            c.repoAuth(repo),
+		Progress:        c.progress,
+		Depth:           depth,
+		ProxyOptions:    c.repoProxyOptions(repo),
+		CABundle:        c.caBundle,
+		InsecureSkipTLS: c.insecureSkipTLS,
+	})
+}
@@ -0,0 +1,28 @@
+package cloner
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// refreshRemote points origin at the current project URL, which changes
+// when the project or one of its groups is renamed or moved.
+func refreshRemote(log *slog.Logger, repo *git.Repository, repoURL string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+
+	remote, ok := cfg.Remotes["origin"]
+	if !ok || (len(remote.URLs) == 1 && remote.URLs[0] == repoURL) {
+		return nil
+	}
+
+	log.Info("update origin url", slog.String("old", strings.Join(remote.URLs, ",")), slog.String("new", repoURL))
+
+	remote.URLs = []string{repoURL}
+
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,93 @@
+package cloner
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	StatusCloned   = "cloned"
+	StatusPulled   = "pulled"
+	StatusSkipped  = "skipped"
+	StatusFailed   = "failed"
+	StatusCorrupt  = "corrupt"
+	StatusEmpty    = "empty"
+	StatusConflict = "conflict"
+	StatusNoAccess = "no_access"
+)
+
+// Result describes what happened to a single repo during the run.
+type Result struct {
+	ProjectID         int           `json:"project_id"`
+	PathWithNamespace string        `json:"path_with_namespace"`
+	SSHURL            string        `json:"ssh_url"`
+	SHA               string        `json:"sha,omitempty"`
+	Status            string        `json:"status"`
+	Commits           int           `json:"commits,omitempty"`
+	Action            string        `json:"action,omitempty"`
+	Duration          time.Duration `json:"duration_ns"`
+
+	started time.Time
+}
+
+func newResult(project *gitlab.Project) *Result {
+	return &Result{
+		ProjectID:         project.ID,
+		PathWithNamespace: project.PathWithNamespace,
+		SSHURL:            project.SSHURLToRepo,
+		started:           time.Now(),
+	}
+}
+
+// record sets the result status, counts it in the stats and keeps the
+// result for Results.
+func (c *Cloner) record(result *Result, status string) {
+	result.Status = status
+	result.Duration = time.Since(result.started)
+
+	c.metrics.observe(status, result.started)
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	switch status {
+	case StatusCloned:
+		c.stats.Cloned++
+	case StatusPulled:
+		c.stats.Pulled++
+	case StatusSkipped:
+		c.stats.Skipped++
+	case StatusFailed:
+		c.stats.Failed++
+	case StatusCorrupt:
+		c.stats.Corrupt++
+	case StatusEmpty:
+		c.stats.Empty++
+	case StatusConflict:
+		c.stats.Conflict++
+	case StatusNoAccess:
+		c.stats.NoAccess++
+	}
+
+	c.stats.Commits += result.Commits
+	c.stats.Duration += result.Duration
+
+	c.results = append(c.results, *result)
+}
+
+// Results returns the per repo results sorted by path.
+func (c *Cloner) Results() []Result {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	results := slices.Clone(c.results)
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return strings.Compare(a.PathWithNamespace, b.PathWithNamespace)
+	})
+
+	return results
+}
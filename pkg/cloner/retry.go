@@ -0,0 +1,107 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/xanzy/go-gitlab"
+)
+
+var retryDelay = time.Second
+
+// retry runs op until it succeeds, fails with a non transient error or
+// maxRetries is exhausted, doubling the delay between attempts. A rate
+// limited API call waits at least for its Retry-After.
+func (c *Cloner) retry(ctx context.Context, log *slog.Logger, op func() error) error {
+	delay := retryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt > c.maxRetries || !isTransient(err) {
+			return err
+		}
+
+		wait := max(delay, retryAfter(err))
+
+		log.Warn("retry",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", wait),
+			slog.String("error", err.Error()),
+		)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+
+		delay *= 2
+	}
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if status := apiStatus(err); status != 0 {
+		return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+
+	var gitErr *githttp.Err
+	if errors.As(err, &gitErr) && gitErr.Response != nil {
+		return gitErr.StatusCode() >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// retryAfter returns the Retry-After of a rate limited API error, or 0
+// when it has none.
+func retryAfter(err error) time.Duration {
+	var apiErr *gitlab.ErrorResponse
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(apiErr.Response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// apiStatus returns the HTTP status of a GitLab API error, or 0 for other
+// errors. The client returns 404 as a plain sentinel error.
+func apiStatus(err error) int {
+	if errors.Is(err, gitlab.ErrNotFound) {
+		return http.StatusNotFound
+	}
+
+	var apiErr *gitlab.ErrorResponse
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		return apiErr.Response.StatusCode
+	}
+
+	return 0
+}
@@ -0,0 +1,116 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func apiError(status int, header http.Header) error {
+	return &gitlab.ErrorResponse{
+		Response: &http.Response{StatusCode: status, Header: header},
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "refused", err: syscall.ECONNREFUSED, want: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: true},
+		{name: "deadline", err: context.DeadlineExceeded, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "api 500", err: apiError(http.StatusInternalServerError, nil), want: true},
+		{name: "api 502", err: apiError(http.StatusBadGateway, nil), want: true},
+		{name: "api 429", err: apiError(http.StatusTooManyRequests, nil), want: true},
+		{name: "api 403", err: apiError(http.StatusForbidden, nil), want: false},
+		{name: "api 404", err: gitlab.ErrNotFound, want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{name: "seconds", err: apiError(http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}}), want: 3 * time.Second},
+		{name: "missing", err: apiError(http.StatusTooManyRequests, http.Header{}), want: 0},
+		{name: "date", err: apiError(http.StatusTooManyRequests, http.Header{"Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}}), want: 0},
+		{name: "not an api error", err: errors.New("boom"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.err); got != tt.want {
+				t.Errorf("retryAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAPI(t *testing.T) {
+	prevDelay := retryDelay
+	retryDelay = 0
+
+	t.Cleanup(func() {
+		retryDelay = prevDelay
+	})
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		flaky      int
+		wantIDs    []int
+		wantHits   int
+	}{
+		{name: "recovers", maxRetries: 3, flaky: 1, wantIDs: []int{11}, wantHits: 2},
+		{name: "exhausted", maxRetries: 1, flaky: 5, wantIDs: []int{}, wantHits: 2},
+		{name: "no retries", maxRetries: 0, flaky: 1, wantIDs: []int{}, wantHits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeGitLab{
+				groups: []*gitlab.Group{testGroup(1, 0, "acme")},
+				projects: map[int][]*gitlab.Project{
+					1: {testProject(11, "acme/a", "")},
+				},
+				flaky: map[string]int{"/groups/1/projects": tt.flaky},
+			}
+
+			c := newTestCloner(t, api.start(t), Options{MaxRetries: tt.maxRetries})
+
+			_ = c.Group(context.Background(), 1)
+
+			if got := queuedIDs(c); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("queued = %v, want %v", got, tt.wantIDs)
+			}
+
+			if got := api.requests("/groups/1/projects"); got != tt.wantHits {
+				t.Errorf("requests = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package cloner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// sparseCheckout limits the worktree of a fresh clone to the sparse paths
+// with the git binary, go-git can not keep a sparse worktree across pulls.
+// The clone is made without a checkout, the reset fills in only the sparse
+// paths. go-git does not write the repository format version, without
+// version 1 git ignores the worktree config sparse-checkout writes to.
+func (c *Cloner) sparseCheckout(ctx context.Context, subPath string) error {
+	commands := [][]string{
+		{"config", "core.repositoryformatversion", "1"},
+		append([]string{"sparse-checkout", "set"}, c.sparsePaths...),
+		{"reset", "--quiet", "--hard", "HEAD"},
+	}
+
+	for _, args := range commands {
+		out, err := runCommand(ctx, subPath, nil, "git", args...)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
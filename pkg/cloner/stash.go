@@ -0,0 +1,75 @@
+package cloner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const unstashTimeout = time.Minute
+
+// stash saves local changes, untracked files included, with the git
+// binary since go-git has no stash. It reports whether anything was
+// stashed, a clean worktree is left alone.
+func (c *Cloner) stash(ctx context.Context, log *slog.Logger, work *git.Worktree, subPath string) (bool, error) {
+	status, err := work.Status()
+	if err != nil {
+		return false, err
+	}
+
+	if status.IsClean() {
+		return false, nil
+	}
+
+	out, err := runCommand(ctx, subPath, nil, "git", "stash", "push", "--include-untracked")
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	log.Info("stashed local changes")
+
+	return true, nil
+}
+
+// dirty reports whether tracked files have local changes, untracked
+// files do not block a pull.
+func dirty(work *git.Worktree) (bool, error) {
+	status, err := work.Status()
+	if err != nil {
+		return false, err
+	}
+
+	for _, file := range status {
+		if file.Worktree != git.Untracked || file.Staging != git.Untracked {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// unstash restores the changes saved by stash. On a conflict they are
+// kept in the stash list. The restore outlives a clone timeout or an
+// interrupt of ctx, it only gets a timeout of its own.
+func (c *Cloner) unstash(ctx context.Context, log *slog.Logger, subPath string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unstashTimeout)
+	defer cancel()
+
+	out, err := runCommand(ctx, subPath, nil, "git", "stash", "pop")
+	if err != nil {
+		log.Error("stash pop error",
+			slog.String("error", err.Error()),
+			slog.String("output", strings.TrimSpace(string(out))),
+		)
+
+		return err
+	}
+
+	log.Info("restored local changes")
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestStashBeforePull(t *testing.T) {
+	// git stash needs an identity for the stash commit.
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "test")
+	}
+
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	src := newSourceRepo(t)
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{StashBeforePull: true})
+	project := testProject(1, "acme/repo", src)
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(c.destDir, "acme", "repo")
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFile(t, repo, src, "NEWS")
+
+	if err := c.gitClone(context.Background(), project, c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "NEWS")); err != nil {
+		t.Errorf("pulled file: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "README"))
+	if err != nil || string(data) != "local\n" {
+		t.Errorf("README = %q, %v, want the local change", data, err)
+	}
+
+	if stats := c.Stats(); stats.Cloned != 1 || stats.Pulled != 1 {
+		t.Errorf("stats = %+v, want 1 cloned and 1 pulled", stats)
+	}
+}
@@ -0,0 +1,71 @@
+package cloner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// loadState reads the project IDs completed by a previous run. A missing
+// state file means nothing was completed yet.
+func (c *Cloner) loadState() error {
+	c.completed = map[int]bool{}
+
+	data, err := os.ReadFile(c.stateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ids := []int{}
+
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		c.completed[id] = true
+	}
+
+	return nil
+}
+
+func (c *Cloner) isCompleted(projectID int) bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	return c.completed[projectID]
+}
+
+// markCompleted records the project and rewrites the state file right
+// away, so a crash keeps the progress made so far.
+func (c *Cloner) markCompleted(projectID int) error {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.completed[projectID] = true
+
+	data, err := json.Marshal(slices.Sorted(maps.Keys(c.completed)))
+	if err != nil {
+		return err
+	}
+
+	tmp := c.stateFile + ".tmp"
+
+	if err := os.MkdirAll(filepath.Dir(c.stateFile), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.stateFile)
+}
@@ -0,0 +1,44 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFileResume(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	if err := os.WriteFile(stateFile, []byte("[1]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	api := sourceGroup(2, newSourceRepo(t))
+
+	c := newTestCloner(t, api.start(t), Options{GroupIDs: []int{1}, StateFile: stateFile})
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range c.Results() {
+		want := StatusCloned
+		if result.ProjectID == 1 {
+			want = StatusSkipped
+		}
+
+		if result.Status != want {
+			t.Errorf("project %d status = %s, want %s", result.ProjectID, result.Status, want)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(c.destDir, "acme", "repo-1")); !os.IsNotExist(err) {
+		t.Errorf("completed project cloned again: %v", err)
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil || string(data) != "[1,2]" {
+		t.Errorf("state file = %q, %v, want [1,2]", data, err)
+	}
+}
@@ -0,0 +1,35 @@
+package cloner
+
+import "time"
+
+// Stats counts the repos by outcome, Duration is the time spent on all of
+// them added up.
+type Stats struct {
+	Cloned     int           `json:"cloned"`
+	Pulled     int           `json:"pulled"`
+	Skipped    int           `json:"skipped"`
+	Failed     int           `json:"failed"`
+	Pruned     int           `json:"pruned"`
+	Corrupt    int           `json:"corrupt"`
+	Empty      int           `json:"empty"`
+	HookFailed int           `json:"hook_failed"`
+	Conflict   int           `json:"conflict"`
+	NoAccess   int           `json:"no_access"`
+	Commits    int           `json:"commits"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+func (c *Cloner) inc(counter *int) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	*counter++
+}
+
+// Stats returns a snapshot of the counters collected so far.
+func (c *Cloner) Stats() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.stats
+}
@@ -0,0 +1,25 @@
+package cloner
+
+import (
+	"context"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// updateSubmodules initializes and updates submodules recursively. It is
+// used instead of CloneOptions.RecurseSubmodules because go-git does not
+// pass the auth method to submodules fetched during a clone. Submodules
+// get the depth resolved for their parent project.
+func (c *Cloner) updateSubmodules(ctx context.Context, work *git.Worktree, depth int) error {
+	subs, err := work.Submodules()
+	if err != nil {
+		return err
+	}
+
+	return subs.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              c.auth,
+		Depth:             depth,
+	})
+}
@@ -0,0 +1,90 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+)
+
+var tagsRefSpec = config.RefSpec("+refs/tags/*:refs/tags/*")
+
+// tagsClone fetches only the tags of the project, no branch is cloned or
+// checked out. Depth limits the history fetched behind every tag, with
+// depth 1 only the tagged commits are kept.
+func (c *Cloner) tagsClone(ctx context.Context, log *slog.Logger, result *Result, project *gitlab.Project, root, subPath, repoURL string, depth int) error {
+	cloned := true
+
+	repo, err := git.PlainInit(subPath, c.bare)
+	if errors.Is(err, git.ErrRepositoryAlreadyExists) {
+		cloned = false
+		repo, err = git.PlainOpen(subPath)
+	}
+
+	if err != nil {
+		log.Error("open repo error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return &OpenError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+	if err != nil && !errors.Is(err, git.ErrRemoteExists) {
+		log.Error("create remote error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return &CloneError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+	}
+
+	if !cloned && c.refreshRemotes {
+		if err := refreshRemote(log, repo, repoURL); err != nil {
+			log.Error("refresh remote error", slog.String("error", err.Error()))
+			c.record(result, StatusFailed)
+
+			return fmt.Errorf("refresh remote %s: %w", project.PathWithNamespace, err)
+		}
+	}
+
+	err = c.retry(ctx, log, func() error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName:      "origin",
+			RefSpecs:        []config.RefSpec{tagsRefSpec},
+			Auth:            c.repoAuth(repo),
+			Progress:        c.progress,
+			Depth:           depth,
+			Tags:            git.AllTags,
+			Force:           true,
+			ProxyOptions:    c.repoProxyOptions(repo),
+			CABundle:        c.caBundle,
+			InsecureSkipTLS: c.insecureSkipTLS,
+		})
+	})
+
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+	case errors.Is(err, git.NoMatchingRefSpecError{}), errors.Is(err, transport.ErrEmptyRemoteRepository):
+		log.Info("no tags")
+	default:
+		log.Error("fetch tags error", slog.String("error", err.Error()))
+		c.record(result, StatusFailed)
+
+		return &PullError{ProjectID: project.ID, Path: project.PathWithNamespace, Err: err}
+	}
+
+	if cloned {
+		log.Info("cloned tags")
+	} else {
+		log.Info("pulled tags")
+	}
+
+	return c.finishClone(ctx, log, result, project, repo, root, subPath, depth, cloned, plumbing.ZeroHash)
+}
@@ -0,0 +1,77 @@
+package cloner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestTagsCloneRunsPostSteps(t *testing.T) {
+	calls := fakeCommands(t)
+
+	src := newSourceRepo(t)
+
+	// The mirror has a branch the tags only clone knows nothing about.
+	mirrorDir := t.TempDir()
+
+	mirror, err := git.PlainClone(filepath.Join(mirrorDir, "acme", "repo.git"), true, &git.CloneOptions{URL: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainOpen(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v1", commitFile(t, repo, src, "NEWS"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	c := newTestCloner(t, "http://127.0.0.1", Options{
+		TagsOnly:  true,
+		GC:        true,
+		LFS:       true,
+		Verify:    true,
+		MirrorTo:  mirrorDir,
+		StateFile: stateFile,
+	})
+
+	if err := c.gitClone(context.Background(), testProject(1, "acme/repo", src), c.destDir, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"git lfs fetch --all",
+		"git gc --aggressive --prune=now --quiet",
+		"git fsck --full --no-progress",
+	} {
+		if !slices.Contains(*calls, want) {
+			t.Errorf("calls = %q, missing %q", *calls, want)
+		}
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil || string(data) != "[1]" {
+		t.Errorf("state file = %q, %v", data, err)
+	}
+
+	if _, err := mirror.Reference(plumbing.NewTagReferenceName("v1"), false); err != nil {
+		t.Errorf("mirror tag: %v", err)
+	}
+
+	if _, err := mirror.Reference(plumbing.NewBranchReferenceName("master"), false); err != nil {
+		t.Errorf("mirror branch pruned: %v", err)
+	}
+
+	if got := c.Stats().Cloned; got != 1 {
+		t.Errorf("cloned = %d, want 1", got)
+	}
+}
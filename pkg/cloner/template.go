@@ -0,0 +1,48 @@
+package cloner
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// DefaultPathTemplate lays repos out by their namespace path.
+const DefaultPathTemplate = "{{.Namespace}}/{{.Path}}"
+
+type pathData struct {
+	ID                int
+	Path              string
+	PathWithNamespace string
+	Namespace         string
+}
+
+// repoPath renders the path template for the project, relative to the
+// destination directory.
+func (c *Cloner) repoPath(project *gitlab.Project, dest string) (string, error) {
+	buf := strings.Builder{}
+
+	err := c.pathTemplate.Execute(&buf, pathData{
+		ID:                project.ID,
+		Path:              project.Path,
+		PathWithNamespace: project.PathWithNamespace,
+		Namespace:         dest,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	subPath := path.Clean(buf.String())
+
+	if c.suffixed[project.ID] {
+		subPath = fmt.Sprintf("%s-%d", subPath, project.ID)
+	}
+
+	if !filepath.IsLocal(subPath) {
+		return "", fmt.Errorf("path %q is outside the destination directory", subPath)
+	}
+
+	return subPath, nil
+}
@@ -0,0 +1,41 @@
+package cloner
+
+import "testing"
+
+func TestRepoPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		dest     string
+		suffixed bool
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", dest: "acme/team", want: "acme/team/repo"},
+		{name: "group root", dest: ".", want: "repo"},
+		{name: "id", template: "{{.ID}}", dest: "acme/team", want: "42"},
+		{name: "flat", template: "{{.Namespace}}-{{.Path}}", dest: "acme/team", want: "acme/team-repo"},
+		{name: "suffixed", dest: "acme/team", suffixed: true, want: "acme/team/repo-42"},
+		{name: "outside", template: "../{{.Path}}", dest: "acme/team", wantErr: true},
+		{name: "absolute", template: "/{{.Path}}", dest: "acme/team", wantErr: true},
+		{name: "missing key", template: "{{.Nope}}", dest: "acme/team", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCloner(t, "http://127.0.0.1", Options{PathTemplate: tt.template})
+
+			project := testProject(42, "acme/team/repo", "")
+			c.suffixed[project.ID] = tt.suffixed
+
+			got, err := c.repoPath(project, tt.dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("repoPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package cloner
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	CheckToken   = "token"
+	CheckGroup   = "group"
+	CheckProject = "project"
+)
+
+// Check is the outcome of validating one configured target, Err is nil
+// when it passed.
+type Check struct {
+	Kind   string
+	Target string
+	Err    error
+}
+
+// Validate checks without cloning that the host is reachable, the token
+// is valid and every configured group and project is accessible.
+func (c *Cloner) Validate(ctx context.Context) []Check {
+	_, _, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+
+	checks := []Check{{
+		Kind:   CheckToken,
+		Target: c.client.BaseURL().Host,
+		Err:    err,
+	}}
+
+	if err != nil {
+		// Nothing else can pass with a bad token or an unreachable host.
+		return checks
+	}
+
+	for _, gid := range c.groupIDs {
+		checks = append(checks, c.checkGroup(ctx, gid, strconv.Itoa(gid)))
+	}
+
+	for _, groupPath := range c.groupFullPaths {
+		checks = append(checks, c.checkGroup(ctx, groupPath, groupPath))
+	}
+
+	for _, pid := range c.projectIDs {
+		_, _, err := c.client.Projects.GetProject(
+			pid,
+			&gitlab.GetProjectOptions{},
+			gitlab.WithContext(ctx),
+		)
+
+		checks = append(checks, Check{
+			Kind:   CheckProject,
+			Target: strconv.Itoa(pid),
+			Err:    err,
+		})
+	}
+
+	return checks
+}
+
+func (c *Cloner) checkGroup(ctx context.Context, gid any, target string) Check {
+	_, _, err := c.client.Groups.GetGroup(
+		gid,
+		&gitlab.GetGroupOptions{},
+		gitlab.WithContext(ctx),
+	)
+
+	return Check{
+		Kind:   CheckGroup,
+		Target: target,
+		Err:    err,
+	}
+}
@@ -0,0 +1,68 @@
+package cloner
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestValidate(t *testing.T) {
+	api := &fakeGitLab{
+		groups: []*gitlab.Group{testGroup(1, 0, "acme")},
+		projects: map[int][]*gitlab.Project{
+			1: {testProject(11, "acme/a", "")},
+		},
+		status: map[string]int{
+			"/projects/12": http.StatusForbidden,
+		},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{
+		GroupIDs:   []int{1, 2},
+		GroupPaths: []string{"acme"},
+		ProjectIDs: []int{11, 12},
+	})
+
+	want := []struct {
+		kind   string
+		target string
+		pass   bool
+	}{
+		{CheckToken, "", true},
+		{CheckGroup, "1", true},
+		{CheckGroup, "2", false},
+		{CheckGroup, "acme", true},
+		{CheckProject, "11", true},
+		{CheckProject, "12", false},
+	}
+
+	checks := c.Validate(context.Background())
+	if len(checks) != len(want) {
+		t.Fatalf("checks = %d, want %d", len(checks), len(want))
+	}
+
+	for i, check := range checks {
+		if check.Kind != want[i].kind || (want[i].target != "" && check.Target != want[i].target) {
+			t.Errorf("check %d = %s %s, want %s %s", i, check.Kind, check.Target, want[i].kind, want[i].target)
+		}
+
+		if pass := check.Err == nil; pass != want[i].pass {
+			t.Errorf("check %s %s pass = %t, want %t", check.Kind, check.Target, pass, want[i].pass)
+		}
+	}
+}
+
+func TestValidateBadToken(t *testing.T) {
+	api := &fakeGitLab{
+		status: map[string]int{"/user": http.StatusUnauthorized},
+	}
+
+	c := newTestCloner(t, api.start(t), Options{GroupIDs: []int{1}})
+
+	checks := c.Validate(context.Background())
+	if len(checks) != 1 || checks[0].Kind != CheckToken || checks[0].Err == nil {
+		t.Errorf("checks = %+v, want a single failed token check", checks)
+	}
+}
@@ -0,0 +1,35 @@
+package cloner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+var errCorrupt = errors.New("repo is corrupt")
+
+// fsck checks the object database with git fsck, catching partial
+// clones left behind by interrupted runs. A missing git binary is
+// reported and skipped.
+func (c *Cloner) fsck(ctx context.Context, log *slog.Logger, subPath string) error {
+	if _, err := lookPath("git"); err != nil {
+		log.Warn("verify skipped", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	out, err := runCommand(ctx, subPath, nil, "git", "fsck", "--full", "--no-progress")
+	if len(out) > 0 {
+		log.Debug("fsck output", slog.String("output", strings.TrimSpace(string(out))))
+	}
+
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: %s", errCorrupt, strings.TrimSpace(string(out)))
+	}
+
+	return err
+}
@@ -0,0 +1,36 @@
+package cloner
+
+import (
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// wikiEnabled reports whether the project has its wiki turned on, older
+// GitLab versions only send the deprecated flag.
+func wikiEnabled(project *gitlab.Project) bool {
+	if project.WikiAccessLevel != "" {
+		return project.WikiAccessLevel != gitlab.DisabledAccessControl
+	}
+
+	return project.WikiEnabled
+}
+
+// wikiProject returns the wiki of the project as a project of its own,
+// cloned next to the repo into a directory with the .wiki suffix.
+func wikiProject(project *gitlab.Project) *gitlab.Project {
+	wiki := *project
+
+	wiki.Path += ".wiki"
+	wiki.PathWithNamespace += ".wiki"
+	wiki.SSHURLToRepo = wikiURL(project.SSHURLToRepo)
+	wiki.HTTPURLToRepo = wikiURL(project.HTTPURLToRepo)
+	wiki.DefaultBranch = ""
+	wiki.EmptyRepo = false
+
+	return &wiki
+}
+
+func wikiURL(repoURL string) string {
+	return strings.TrimSuffix(repoURL, ".git") + ".wiki.git"
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// logPlan prints the repos a dry run would clone and pull.
+func logPlan(results []cloner.Result) {
+	planned := map[string]int{}
+
+	for _, result := range results {
+		if result.Action == "" {
+			continue
+		}
+
+		planned[result.Action]++
+
+		slog.Info("plan",
+			slog.String("action", result.Action),
+			slog.String("path", result.PathWithNamespace),
+		)
+	}
+
+	slog.Info("plan summary",
+		slog.Int("clone", planned[cloner.ActionClone]),
+		slog.Int("pull", planned[cloner.ActionPull]),
+	)
+}
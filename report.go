@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// runReport is the machine readable description of a run, tokens are
+// never included.
+type runReport struct {
+	StartedAt time.Time       `json:"started_at"`
+	Duration  float64         `json:"duration_seconds"`
+	Config    reportConfig    `json:"config"`
+	Stats     cloner.Stats    `json:"stats"`
+	Results   []cloner.Result `json:"results"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type reportConfig struct {
+	DestDir     string           `json:"dest_dir"`
+	AuthMethod  string           `json:"auth_method"`
+	All         bool             `json:"all"`
+	DryRun      bool             `json:"dry_run"`
+	Concurrency int              `json:"concurrency"`
+	Instances   []reportInstance `json:"instances"`
+}
+
+type reportInstance struct {
+	GitlabHost string   `json:"gitlab_host"`
+	GroupIDs   []int    `json:"group_ids"`
+	GroupPaths []string `json:"group_paths"`
+	ProjectIDs []int    `json:"project_ids"`
+}
+
+func newReportConfig(opts cloner.Options, instances []InstanceConfig) reportConfig {
+	config := reportConfig{
+		DestDir:     opts.DestDir,
+		AuthMethod:  opts.AuthMethod,
+		All:         opts.All,
+		DryRun:      opts.DryRun,
+		Concurrency: opts.Concurrency,
+	}
+
+	for _, instance := range instances {
+		config.Instances = append(config.Instances, reportInstance{
+			GitlabHost: instance.GitlabHost,
+			GroupIDs:   instance.GroupIDs,
+			GroupPaths: instance.GroupPaths,
+			ProjectIDs: instance.ProjectIDs,
+		})
+	}
+
+	return config
+}
+
+func writeReport(w io.Writer, report runReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
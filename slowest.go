@@ -0,0 +1,26 @@
+package main
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// logSlowest logs the n repos that took the longest.
+func logSlowest(results []cloner.Result, n int) {
+	results = slices.Clone(results)
+
+	slices.SortStableFunc(results, func(a, b cloner.Result) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+
+	for _, result := range results[:min(n, len(results))] {
+		slog.Info("slow repo",
+			slog.String("path", result.PathWithNamespace),
+			slog.String("status", result.Status),
+			slog.Duration("duration", result.Duration),
+		)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readTargets reads groups and projects, one per line as g:<id or path>
+// or p:<id>. Blank lines and lines starting with # are ignored.
+func readTargets(r io.Reader) (groupIDs []int, groupPaths []string, projectIDs []int, err error) {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		kind, value, _ := strings.Cut(entry, ":")
+		value = strings.TrimSpace(value)
+
+		switch kind {
+		case "g":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				groupPaths = append(groupPaths, value)
+
+				continue
+			}
+
+			groupIDs = append(groupIDs, id)
+		case "p":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("line %d: invalid project id %q", line, value)
+			}
+
+			projectIDs = append(projectIDs, id)
+		default:
+			return nil, nil, nil, fmt.Errorf("line %d: unknown entry %q, want g: or p: prefix", line, entry)
+		}
+	}
+
+	return groupIDs, groupPaths, projectIDs, scanner.Err()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		groupIDs   []int
+		groupPaths []string
+		projectIDs []int
+		wantErr    bool
+	}{
+		{
+			name:       "mixed",
+			input:      "# ignored\ng:1\n\n  g: acme/team \np:42\ng:2\n",
+			groupIDs:   []int{1, 2},
+			groupPaths: []string{"acme/team"},
+			projectIDs: []int{42},
+		},
+		{name: "empty", input: "\n# only a comment\n"},
+		{name: "invalid project", input: "p:acme/repo\n", wantErr: true},
+		{name: "unknown prefix", input: "x:1\n", wantErr: true},
+		{name: "no prefix", input: "1\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupIDs, groupPaths, projectIDs, err := readTargets(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+
+			if !slices.Equal(groupIDs, tt.groupIDs) {
+				t.Errorf("group ids = %v, want %v", groupIDs, tt.groupIDs)
+			}
+
+			if !slices.Equal(groupPaths, tt.groupPaths) {
+				t.Errorf("group paths = %q, want %q", groupPaths, tt.groupPaths)
+			}
+
+			if !slices.Equal(projectIDs, tt.projectIDs) {
+				t.Errorf("project ids = %v, want %v", projectIDs, tt.projectIDs)
+			}
+		})
+	}
+}
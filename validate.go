@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/a-kataev/gitlab-repo-cloner/pkg/cloner"
+)
+
+// logValidate prints a pass or fail line per check and returns the
+// number of failed checks.
+func logValidate(log *slog.Logger, checks []cloner.Check) int {
+	failed := 0
+
+	for _, check := range checks {
+		if check.Err != nil {
+			failed++
+
+			log.Error("validate",
+				slog.String("check", check.Kind),
+				slog.String("target", check.Target),
+				slog.String("status", "fail"),
+				slog.String("error", check.Err.Error()),
+			)
+
+			continue
+		}
+
+		log.Info("validate",
+			slog.String("check", check.Kind),
+			slog.String("target", check.Target),
+			slog.String("status", "pass"),
+		)
+	}
+
+	log.Info("validate summary",
+		slog.Int("passed", len(checks)-failed),
+		slog.Int("failed", failed),
+	)
+
+	return failed
+}